@@ -0,0 +1,315 @@
+// Package bitset contains a dense set of non-negative integers.
+package bitset
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/bradenaw/juniper/iterator"
+)
+
+const wordSize = 64
+
+// Bitset is a set of non-negative integers stored as one bit each, which grows as needed to hold
+// its largest member.
+//
+// Bitset uses memory proportional to its largest member rather than to its number of members, so it
+// is best for sets that are dense in a small range of integers.
+//
+// The zero value is an empty set ready to use. Bitset is not safe for concurrent use.
+type Bitset struct {
+	words []uint64
+}
+
+// New returns an empty Bitset with room for integers in [0, n) before it needs to grow.
+func New(n int) *Bitset {
+	return &Bitset{words: make([]uint64, 0, (n+wordSize-1)/wordSize)}
+}
+
+// Clone returns a copy of b.
+func (b *Bitset) Clone() *Bitset {
+	return &Bitset{words: append([]uint64(nil), b.words...)}
+}
+
+// Set adds i to the set.
+//
+// Panics if i < 0.
+func (b *Bitset) Set(i int) {
+	w := wordIndex(i)
+	b.grow(w + 1)
+	b.words[w] |= 1 << (uint(i) % wordSize)
+}
+
+// Clear removes i from the set.
+//
+// Panics if i < 0.
+func (b *Bitset) Clear(i int) {
+	w := wordIndex(i)
+	if w >= len(b.words) {
+		return
+	}
+	b.words[w] &^= 1 << (uint(i) % wordSize)
+}
+
+// Flip adds i to the set if it is absent, and removes it if it is present.
+//
+// Panics if i < 0.
+func (b *Bitset) Flip(i int) {
+	w := wordIndex(i)
+	b.grow(w + 1)
+	b.words[w] ^= 1 << (uint(i) % wordSize)
+}
+
+// Test returns true if i is in the set.
+//
+// Panics if i < 0.
+func (b *Bitset) Test(i int) bool {
+	w := wordIndex(i)
+	if w >= len(b.words) {
+		return false
+	}
+	return b.words[w]&(1<<(uint(i)%wordSize)) != 0
+}
+
+// Count returns the number of integers in the set.
+func (b *Bitset) Count() int {
+	n := 0
+	for _, word := range b.words {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+// Reset removes all integers from the set.
+func (b *Bitset) Reset() {
+	b.words = b.words[:0]
+}
+
+// NextSet returns the smallest member of the set that is >= i, or false in the second return if
+// there is none.
+func (b *Bitset) NextSet(i int) (int, bool) {
+	if i < 0 {
+		i = 0
+	}
+	w := i / wordSize
+	if w >= len(b.words) {
+		return 0, false
+	}
+	word := b.words[w] >> (uint(i) % wordSize)
+	if word != 0 {
+		return i + bits.TrailingZeros64(word), true
+	}
+	for w++; w < len(b.words); w++ {
+		if b.words[w] != 0 {
+			return w*wordSize + bits.TrailingZeros64(b.words[w]), true
+		}
+	}
+	return 0, false
+}
+
+// PrevSet returns the largest member of the set that is <= i, or false in the second return if
+// there is none.
+func (b *Bitset) PrevSet(i int) (int, bool) {
+	if i < 0 {
+		return 0, false
+	}
+	w := i / wordSize
+	if w >= len(b.words) {
+		w = len(b.words) - 1
+		i = w*wordSize + wordSize - 1
+	}
+	if w < 0 {
+		return 0, false
+	}
+	word := b.words[w] << (wordSize - 1 - uint(i)%wordSize)
+	if word != 0 {
+		return i - bits.LeadingZeros64(word), true
+	}
+	for w--; w >= 0; w-- {
+		if b.words[w] != 0 {
+			return w*wordSize + wordSize - 1 - bits.LeadingZeros64(b.words[w]), true
+		}
+	}
+	return 0, false
+}
+
+// Iterate returns an iterator over the members of the set in ascending order.
+//
+// The set may be safely modified during iteration. The iterator continues from the last integer it
+// yielded, so it sees integers added after its current position but not necessarily a consistent
+// snapshot of the set.
+func (b *Bitset) Iterate() iterator.Iterator[int] {
+	return &bitsetIterator{b: b}
+}
+
+type bitsetIterator struct {
+	b    *Bitset
+	next int
+}
+
+func (iter *bitsetIterator) Next() (int, bool) {
+	i, ok := iter.b.NextSet(iter.next)
+	if !ok {
+		iter.next = len(iter.b.words) * wordSize
+		return 0, false
+	}
+	iter.next = i + 1
+	return i, true
+}
+
+// Equal returns true if b and other have the same members.
+func (b *Bitset) Equal(other *Bitset) bool {
+	short, long := b.words, other.words
+	if len(short) > len(long) {
+		short, long = long, short
+	}
+	for i := range short {
+		if short[i] != long[i] {
+			return false
+		}
+	}
+	for _, word := range long[len(short):] {
+		if word != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// And removes all members of b that are not in other.
+func (b *Bitset) And(other *Bitset) {
+	if len(other.words) < len(b.words) {
+		b.words = b.words[:len(other.words)]
+	}
+	for i := range b.words {
+		b.words[i] &= other.words[i]
+	}
+}
+
+// Or adds all members of other to b.
+func (b *Bitset) Or(other *Bitset) {
+	b.grow(len(other.words))
+	for i, word := range other.words {
+		b.words[i] |= word
+	}
+}
+
+// Xor makes b contain the integers that are in exactly one of b and other.
+func (b *Bitset) Xor(other *Bitset) {
+	b.grow(len(other.words))
+	for i, word := range other.words {
+		b.words[i] ^= word
+	}
+}
+
+// AndNot removes all members of other from b.
+func (b *Bitset) AndNot(other *Bitset) {
+	n := len(b.words)
+	if len(other.words) < n {
+		n = len(other.words)
+	}
+	for i := 0; i < n; i++ {
+		b.words[i] &^= other.words[i]
+	}
+}
+
+// And returns a new Bitset containing the integers that are in both a and b.
+func And(a, b *Bitset) *Bitset {
+	out := a.Clone()
+	out.And(b)
+	return out
+}
+
+// Or returns a new Bitset containing the integers that are in either a or b.
+func Or(a, b *Bitset) *Bitset {
+	out := a.Clone()
+	out.Or(b)
+	return out
+}
+
+// Xor returns a new Bitset containing the integers that are in exactly one of a and b.
+func Xor(a, b *Bitset) *Bitset {
+	out := a.Clone()
+	out.Xor(b)
+	return out
+}
+
+// AndNot returns a new Bitset containing the integers that are in a but not in b.
+func AndNot(a, b *Bitset) *Bitset {
+	out := a.Clone()
+	out.AndNot(b)
+	return out
+}
+
+// MarshalBinary encodes the set as a sequence of little-endian 64-bit words, where bit j of word
+// i is set if i*64+j is a member. Trailing zero words are omitted.
+func (b *Bitset) MarshalBinary() ([]byte, error) {
+	n := len(b.words)
+	for n > 0 && b.words[n-1] == 0 {
+		n--
+	}
+	out := make([]byte, n*8)
+	for i, word := range b.words[:n] {
+		binary.LittleEndian.PutUint64(out[i*8:], word)
+	}
+	return out, nil
+}
+
+// UnmarshalBinary replaces the contents of b with the set encoded in data by MarshalBinary.
+func (b *Bitset) UnmarshalBinary(data []byte) error {
+	if len(data)%8 != 0 {
+		return errors.New("bitset data must be a multiple of 8 bytes")
+	}
+	b.words = make([]uint64, len(data)/8)
+	for i := range b.words {
+		b.words[i] = binary.LittleEndian.Uint64(data[i*8:])
+	}
+	return nil
+}
+
+// String returns the members of the set formatted like {1, 5, 7}.
+func (b *Bitset) String() string {
+	var sb strings.Builder
+	sb.WriteByte('{')
+	iter := b.Iterate()
+	first := true
+	for {
+		i, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if !first {
+			sb.WriteString(", ")
+		}
+		first = false
+		sb.WriteString(strconv.Itoa(i))
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// grow makes sure b has at least n words.
+func (b *Bitset) grow(n int) {
+	if n <= len(b.words) {
+		return
+	}
+	if n <= cap(b.words) {
+		old := len(b.words)
+		b.words = b.words[:n]
+		for i := old; i < n; i++ {
+			b.words[i] = 0
+		}
+		return
+	}
+	b.words = append(b.words, make([]uint64, n-len(b.words))...)
+}
+
+func wordIndex(i int) int {
+	if i < 0 {
+		panic("negative bitset index")
+	}
+	return i / wordSize
+}
@@ -0,0 +1,192 @@
+package bitset
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bradenaw/juniper/internal/fuzz"
+	"github.com/bradenaw/juniper/internal/require2"
+	"github.com/bradenaw/juniper/iterator"
+	"github.com/bradenaw/juniper/xmaps"
+	"github.com/bradenaw/juniper/xsort"
+)
+
+func FuzzBitset(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte) {
+		// Two sets so that the binary operations have something to work with.
+		sets := [2]*Bitset{{}, New(100)}
+		oracles := [2]xmaps.Set[int]{{}, {}}
+
+		sorted := func(s xmaps.Set[int]) []int {
+			out := make([]int, 0, len(s))
+			for x := range s {
+				out = append(out, x)
+			}
+			xsort.Slice(out, xsort.OrderedLess[int])
+			return out
+		}
+		fromOracle := func(s xmaps.Set[int]) *Bitset {
+			var out Bitset
+			for x := range s {
+				out.Set(x)
+			}
+			return &out
+		}
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				for j := range sets {
+					expected := sorted(oracles[j])
+					require2.SlicesEqual(t, expected, iterator.Collect(sets[j].Iterate()))
+					require2.Equal(t, len(expected), sets[j].Count())
+					require2.True(t, sets[j].Equal(fromOracle(oracles[j])))
+
+					data, err := sets[j].MarshalBinary()
+					require2.NoError(t, err)
+					var decoded Bitset
+					require2.NoError(t, decoded.UnmarshalBinary(data))
+					require2.SlicesEqual(t, expected, iterator.Collect(decoded.Iterate()))
+				}
+			},
+			func(which bool, x byte) {
+				j := boolIndex(which)
+				t.Logf("sets[%d].Set(%d)", j, x)
+				sets[j].Set(int(x))
+				oracles[j].Add(int(x))
+			},
+			func(which bool, x byte) {
+				j := boolIndex(which)
+				t.Logf("sets[%d].Clear(%d)", j, x)
+				sets[j].Clear(int(x))
+				oracles[j].Remove(int(x))
+			},
+			func(which bool, x byte) {
+				j := boolIndex(which)
+				t.Logf("sets[%d].Flip(%d)", j, x)
+				sets[j].Flip(int(x))
+				if oracles[j].Contains(int(x)) {
+					oracles[j].Remove(int(x))
+				} else {
+					oracles[j].Add(int(x))
+				}
+			},
+			func(which bool, x byte) {
+				j := boolIndex(which)
+				t.Logf("sets[%d].Test(%d)", j, x)
+				require2.Equal(t, oracles[j].Contains(int(x)), sets[j].Test(int(x)))
+			},
+			func(which bool, x uint16) {
+				j := boolIndex(which)
+				i := int(x%300) - 10
+				expectedNext, expectedNextOk := 0, false
+				expectedPrev, expectedPrevOk := 0, false
+				for _, y := range sorted(oracles[j]) {
+					if y >= i && !expectedNextOk {
+						expectedNext, expectedNextOk = y, true
+					}
+					if y <= i {
+						expectedPrev, expectedPrevOk = y, true
+					}
+				}
+				next, ok := sets[j].NextSet(i)
+				t.Logf("sets[%d].NextSet(%d) -> %d, %t", j, i, next, ok)
+				require2.Equal(t, expectedNextOk, ok)
+				require2.Equal(t, expectedNext, next)
+				prev, ok := sets[j].PrevSet(i)
+				t.Logf("sets[%d].PrevSet(%d) -> %d, %t", j, i, prev, ok)
+				require2.Equal(t, expectedPrevOk, ok)
+				require2.Equal(t, expectedPrev, prev)
+			},
+			func(which bool, op byte, inPlace bool) {
+				j := boolIndex(which)
+				a, b := sets[j], sets[1-j]
+				oa, ob := oracles[j], oracles[1-j]
+				var result *Bitset
+				var expected xmaps.Set[int]
+				switch op % 4 {
+				case 0:
+					t.Logf("sets[%d].And(sets[%d]), inPlace=%t", j, 1-j, inPlace)
+					expected = xmaps.Intersection(oa, ob)
+					if inPlace {
+						a.And(b)
+					} else {
+						result = And(a, b)
+					}
+				case 1:
+					t.Logf("sets[%d].Or(sets[%d]), inPlace=%t", j, 1-j, inPlace)
+					expected = xmaps.Union(oa, ob)
+					if inPlace {
+						a.Or(b)
+					} else {
+						result = Or(a, b)
+					}
+				case 2:
+					t.Logf("sets[%d].Xor(sets[%d]), inPlace=%t", j, 1-j, inPlace)
+					expected = xmaps.Union(
+						xmaps.Difference(oa, ob),
+						xmaps.Difference(ob, oa),
+					)
+					if inPlace {
+						a.Xor(b)
+					} else {
+						result = Xor(a, b)
+					}
+				case 3:
+					t.Logf("sets[%d].AndNot(sets[%d]), inPlace=%t", j, 1-j, inPlace)
+					expected = xmaps.Difference(oa, ob)
+					if inPlace {
+						a.AndNot(b)
+					} else {
+						result = AndNot(a, b)
+					}
+				}
+				if inPlace {
+					oracles[j] = expected
+				} else {
+					require2.SlicesEqual(t, sorted(expected), iterator.Collect(result.Iterate()))
+				}
+			},
+			func(which bool) {
+				j := boolIndex(which)
+				t.Logf("sets[%d].Reset()", j)
+				sets[j].Reset()
+				oracles[j] = xmaps.Set[int]{}
+			},
+		)
+	})
+}
+
+func boolIndex(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestUnmarshalBinaryInvalid(t *testing.T) {
+	var b Bitset
+	require2.Error(t, b.UnmarshalBinary([]byte{1, 2, 3}))
+}
+
+func ExampleBitset() {
+	var b Bitset
+	b.Set(3)
+	b.Set(64)
+	b.Set(100)
+
+	var other Bitset
+	other.Set(64)
+	other.Set(65)
+
+	fmt.Println(b.Count())
+	fmt.Println(Or(&b, &other))
+	fmt.Println(And(&b, &other))
+	fmt.Println(b.NextSet(4))
+
+	// Output:
+	// 3
+	// {3, 64, 65, 100}
+	// {64}
+	// 64 true
+}
@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"github.com/bradenaw/juniper/container/xlist"
+	"github.com/bradenaw/juniper/xmath"
+)
+
+type arcList int
+
+const (
+	arcT1 arcList = iota
+	arcT2
+	arcB1
+	arcB2
+)
+
+// ARC is a cache using the Adaptive Replacement Cache policy
+// (https://www.usenix.org/legacy/events/fast03/tech/full_papers/megiddo/megiddo.pdf).
+//
+// ARC splits the cache between entries that have been accessed once recently and entries that
+// have been accessed more than once, and remembers the keys of entries recently evicted from each
+// without their values. Re-adding a remembered key shifts capacity towards the side it was evicted
+// from, so the split adapts to the workload. Keys that are only seen once cannot evict entries
+// that have been accessed more than once beyond what the recent side has been allotted.
+//
+// Get, Peek, Put, and Remove take O(1) time.
+//
+// ARC is not safe for concurrent use.
+type ARC[K comparable, V any] struct {
+	capacity int
+	// Target length of t1.
+	p int
+
+	// In each of these, front is least-recently used.
+	//
+	// Resident entries that have been accessed once recently.
+	t1 xlist.List[arcEntry[K, V]]
+	// Resident entries that have been accessed at least twice recently.
+	t2 xlist.List[arcEntry[K, V]]
+	// Keys recently evicted from t1, without values.
+	b1 xlist.List[arcEntry[K, V]]
+	// Keys recently evicted from t2, without values.
+	b2 xlist.List[arcEntry[K, V]]
+
+	m map[K]*xlist.Node[arcEntry[K, V]]
+}
+
+type arcEntry[K any, V any] struct {
+	k    K
+	v    V
+	list arcList
+}
+
+// NewARC returns an empty ARC that holds at most capacity entries.
+func NewARC[K comparable, V any](capacity int) *ARC[K, V] {
+	return &ARC[K, V]{
+		capacity: capacity,
+		m:        make(map[K]*xlist.Node[arcEntry[K, V]]),
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (c *ARC[K, V]) Len() int {
+	return c.t1.Len() + c.t2.Len()
+}
+
+// Get returns the value for k if it is present, otherwise returns false in the second return.
+func (c *ARC[K, V]) Get(k K) (V, bool) {
+	node, ok := c.resident(k)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.move(node, arcT2)
+	return node.Value.v, true
+}
+
+// Peek returns the value for k if it is present without counting as an access of k.
+func (c *ARC[K, V]) Peek(k K) (V, bool) {
+	node, ok := c.resident(k)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return node.Value.v, true
+}
+
+// Put adds an entry for k with value v, replacing any existing entry.
+func (c *ARC[K, V]) Put(k K, v V) {
+	if c.capacity <= 0 {
+		return
+	}
+	node, ok := c.m[k]
+	if ok {
+		switch node.Value.list {
+		case arcT1, arcT2:
+			node.Value.v = v
+			c.move(node, arcT2)
+			return
+		case arcB1:
+			c.p = xmath.Min(c.capacity, c.p+xmath.Max(c.b2.Len()/c.b1.Len(), 1))
+			c.replace(false)
+			node.Value.v = v
+			c.move(node, arcT2)
+			return
+		case arcB2:
+			c.p = xmath.Max(0, c.p-xmath.Max(c.b1.Len()/c.b2.Len(), 1))
+			c.replace(true)
+			node.Value.v = v
+			c.move(node, arcT2)
+			return
+		}
+	}
+
+	if c.t1.Len()+c.b1.Len() >= c.capacity {
+		if c.b1.Len() > 0 {
+			c.discard(c.b1.Front())
+			c.replace(false)
+		} else {
+			c.discard(c.t1.Front())
+		}
+	} else if c.t1.Len()+c.t2.Len()+c.b1.Len()+c.b2.Len() >= c.capacity {
+		if c.t1.Len()+c.t2.Len()+c.b1.Len()+c.b2.Len() >= 2*c.capacity {
+			c.discard(c.b2.Front())
+		}
+		c.replace(false)
+	}
+	c.m[k] = c.t1.PushBack(arcEntry[K, V]{k: k, v: v, list: arcT1})
+}
+
+// Remove removes the entry for k, if present. Returns true if an entry was removed.
+func (c *ARC[K, V]) Remove(k K) bool {
+	node, ok := c.resident(k)
+	if !ok {
+		return false
+	}
+	c.discard(node)
+	return true
+}
+
+func (c *ARC[K, V]) resident(k K) (*xlist.Node[arcEntry[K, V]], bool) {
+	node, ok := c.m[k]
+	if !ok || (node.Value.list != arcT1 && node.Value.list != arcT2) {
+		return nil, false
+	}
+	return node, true
+}
+
+// replace makes room for one more resident entry if the cache is full, by moving the
+// least-recently used entry of t1 or t2 to the corresponding ghost list.
+func (c *ARC[K, V]) replace(inB2 bool) {
+	if c.t1.Len()+c.t2.Len() < c.capacity {
+		return
+	}
+	if c.t1.Len() > 0 && (c.t1.Len() > c.p || (inB2 && c.t1.Len() == c.p) || c.t2.Len() == 0) {
+		c.move(c.t1.Front(), arcB1)
+	} else {
+		c.move(c.t2.Front(), arcB2)
+	}
+}
+
+func (c *ARC[K, V]) list(which arcList) *xlist.List[arcEntry[K, V]] {
+	switch which {
+	case arcT1:
+		return &c.t1
+	case arcT2:
+		return &c.t2
+	case arcB1:
+		return &c.b1
+	default:
+		return &c.b2
+	}
+}
+
+// move moves node to the most-recently used position of the list to.
+func (c *ARC[K, V]) move(node *xlist.Node[arcEntry[K, V]], to arcList) {
+	c.list(node.Value.list).MoveToList(node, c.list(to))
+	node.Value.list = to
+	if to == arcB1 || to == arcB2 {
+		// Don't keep the value alive.
+		var zero V
+		node.Value.v = zero
+	}
+}
+
+func (c *ARC[K, V]) discard(node *xlist.Node[arcEntry[K, V]]) {
+	c.list(node.Value.list).Remove(node)
+	delete(c.m, node.Value.k)
+}
@@ -0,0 +1,33 @@
+// Package cache contains key-value caches with a fixed capacity that use different policies to
+// decide which entries to evict, behind a common interface.
+//
+// Plain least-recently-used eviction is available in container/lru. The policies in this package
+// are scan-resistant, meaning that a one-off pass over many keys that are never accessed again
+// does not flush the frequently-used entries out of the cache.
+package cache
+
+import (
+	"github.com/bradenaw/juniper/container/lru"
+)
+
+// Cache is a key-value cache with a fixed capacity that evicts entries according to some policy.
+type Cache[K comparable, V any] interface {
+	// Get returns the value for k if it is present, otherwise returns false in the second return.
+	// Get counts as an access of k for the purposes of the eviction policy.
+	Get(k K) (V, bool)
+	// Peek returns the value for k if it is present, otherwise returns false in the second return.
+	// Peek does not count as an access of k.
+	Peek(k K) (V, bool)
+	// Put adds an entry for k with value v, replacing any existing entry, and evicts entries
+	// according to the policy if the cache is over capacity. Put counts as an access of k.
+	Put(k K, v V)
+	// Remove removes the entry for k, if present. Returns true if an entry was removed.
+	Remove(k K) bool
+	// Len returns the number of entries in the cache.
+	Len() int
+}
+
+var _ Cache[int, int] = (*lru.Cache[int, int])(nil)
+var _ Cache[int, int] = (*TwoQueue[int, int])(nil)
+var _ Cache[int, int] = (*ARC[int, int])(nil)
+var _ Cache[int, int] = (*TinyLFU[int, int])(nil)
@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+const (
+	traceCacheSize = 1000
+	traceLen       = 200_000
+)
+
+// Access traces to replay against each policy. Each is a sequence of keys.
+var traces = []struct {
+	name string
+	keys func() []uint64
+}{
+	{
+		// Skewed accesses to a key space larger than the cache, where a small set of keys is
+		// very popular.
+		"Zipf",
+		func() []uint64 {
+			r := rand.New(rand.NewSource(0))
+			zipf := rand.NewZipf(r, 1.1, 1, 100*traceCacheSize)
+			keys := make([]uint64, traceLen)
+			for i := range keys {
+				keys[i] = zipf.Uint64()
+			}
+			return keys
+		},
+	},
+	{
+		// The same as Zipf, except periodically interrupted by a sequential scan over keys that
+		// are never accessed again, like a periodic full-table scan.
+		"ZipfWithScans",
+		func() []uint64 {
+			r := rand.New(rand.NewSource(0))
+			zipf := rand.NewZipf(r, 1.1, 1, 100*traceCacheSize)
+			keys := make([]uint64, 0, traceLen)
+			scanKey := uint64(1 << 32)
+			for len(keys) < traceLen {
+				for i := 0; i < 10*traceCacheSize && len(keys) < traceLen; i++ {
+					keys = append(keys, zipf.Uint64())
+				}
+				for i := 0; i < 2*traceCacheSize && len(keys) < traceLen; i++ {
+					keys = append(keys, scanKey)
+					scanKey++
+				}
+			}
+			return keys
+		},
+	},
+	{
+		// Repeatedly loops over a key space slightly larger than the cache, which is the worst case
+		// for LRU since every access misses.
+		"Loop",
+		func() []uint64 {
+			keys := make([]uint64, traceLen)
+			for i := range keys {
+				keys[i] = uint64(i % (traceCacheSize + traceCacheSize/10))
+			}
+			return keys
+		},
+	},
+}
+
+// replay accesses each key of trace in order, putting it into c whenever it is missing. It returns
+// the fraction of accesses that were hits.
+func replay(c Cache[uint64, int], trace []uint64) float64 {
+	hits := 0
+	for _, k := range trace {
+		_, ok := c.Get(k)
+		if ok {
+			hits++
+		} else {
+			c.Put(k, 0)
+		}
+	}
+	return float64(hits) / float64(len(trace))
+}
+
+func TestScanResistance(t *testing.T) {
+	var trace []uint64
+	for _, tr := range traces {
+		if tr.name == "ZipfWithScans" {
+			trace = tr.keys()
+		}
+	}
+
+	hitRatios := make(map[string]float64)
+	for _, policy := range policies {
+		hitRatios[policy.name] = replay(policy.new(traceCacheSize), trace)
+		t.Logf("%s: %.4f", policy.name, hitRatios[policy.name])
+	}
+	for _, policy := range policies {
+		if policy.name == "LRU" {
+			continue
+		}
+		if hitRatios[policy.name] <= hitRatios["LRU"] {
+			t.Errorf(
+				"expected %s to have a better hit ratio than LRU: %.4f <= %.4f",
+				policy.name,
+				hitRatios[policy.name],
+				hitRatios["LRU"],
+			)
+		}
+	}
+}
+
+// Run with, e.g., go test --bench HitRatio ./container/cache to compare policies. Reports the hit
+// ratio of each policy on each trace as the hit-ratio metric.
+func BenchmarkHitRatio(b *testing.B) {
+	for _, tr := range traces {
+		trace := tr.keys()
+		for _, policy := range policies {
+			b.Run(fmt.Sprintf("Trace=%s/Policy=%s", tr.name, policy.name), func(b *testing.B) {
+				hitRatio := 0.0
+				for i := 0; i < b.N; i++ {
+					hitRatio = replay(policy.new(traceCacheSize), trace)
+				}
+				b.ReportMetric(hitRatio, "hit-ratio")
+			})
+		}
+	}
+}
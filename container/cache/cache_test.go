@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/bradenaw/juniper/container/lru"
+	"github.com/bradenaw/juniper/internal/fuzz"
+	"github.com/bradenaw/juniper/internal/hash"
+	"github.com/bradenaw/juniper/internal/require2"
+)
+
+func hashUint64(x uint64) uint64 {
+	return hash.Mix(x)
+}
+
+var policies = []struct {
+	name string
+	new  func(capacity int) Cache[uint64, int]
+}{
+	{
+		"LRU",
+		func(capacity int) Cache[uint64, int] {
+			return lru.New(capacity, lru.Options[uint64, int]{})
+		},
+	},
+	{
+		"2Q",
+		func(capacity int) Cache[uint64, int] { return NewTwoQueue[uint64, int](capacity) },
+	},
+	{
+		"ARC",
+		func(capacity int) Cache[uint64, int] { return NewARC[uint64, int](capacity) },
+	},
+	{
+		"TinyLFU",
+		func(capacity int) Cache[uint64, int] {
+			return NewTinyLFU[uint64, int](capacity, hashUint64)
+		},
+	},
+}
+
+func FuzzCache(f *testing.F) {
+	f.Fuzz(func(t *testing.T, capacity byte, b []byte) {
+		capacity = capacity % 16
+		for _, policy := range policies {
+			t.Logf("%s(%d)", policy.name, capacity)
+			c := policy.new(int(capacity))
+			// The last value put for each key. The cache may have evicted any of these, but must
+			// not return anything else.
+			oracle := make(map[uint64]int)
+			ctr := 0
+
+			check := func(k uint64, v int, ok bool) {
+				if ok {
+					expected, oracleOk := oracle[k]
+					require2.True(t, oracleOk)
+					require2.Equal(t, expected, v)
+				}
+			}
+
+			fuzz.Operations(
+				b,
+				func() { // check
+					require2.LessOrEqual(t, c.Len(), int(capacity))
+					present := 0
+					for k := range oracle {
+						if _, ok := c.Peek(k); ok {
+							present++
+						}
+					}
+					require2.Equal(t, present, c.Len())
+				},
+				func(k byte) {
+					key := uint64(k % 32)
+					t.Logf("Put(%d, %d)", key, ctr)
+					c.Put(key, ctr)
+					oracle[key] = ctr
+					ctr++
+					if capacity > 0 {
+						// The most recently put key is always present.
+						v, ok := c.Peek(key)
+						require2.True(t, ok)
+						check(key, v, ok)
+					}
+				},
+				func(k byte) {
+					key := uint64(k % 32)
+					t.Logf("Get(%d)", key)
+					v, ok := c.Get(key)
+					check(key, v, ok)
+				},
+				func(k byte) {
+					key := uint64(k % 32)
+					t.Logf("Remove(%d)", key)
+					_, wasPresent := c.Peek(key)
+					require2.Equal(t, wasPresent, c.Remove(key))
+					_, ok := c.Peek(key)
+					require2.True(t, !ok)
+					delete(oracle, key)
+				},
+			)
+		}
+	})
+}
@@ -0,0 +1,250 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bradenaw/juniper/container/lru"
+	"github.com/bradenaw/juniper/xsync"
+)
+
+// ErrClosed is returned from LoadingCache.Get when a load is needed after the cache has been
+// closed.
+var ErrClosed = errors.New("loading cache closed")
+
+// LoadingOptions configures a LoadingCache. The zero value is a valid configuration, in which
+// values never expire or refresh and errors are not cached.
+type LoadingOptions struct {
+	// RefreshAfter is how long after loading a value Get starts refreshing it in the background.
+	// Until the refresh finishes, Get continues to return the stale value. Zero means values are
+	// never refreshed.
+	RefreshAfter time.Duration
+	// ExpireAfter is how long after loading a value Get stops returning it and instead waits for it
+	// to be loaded again. Zero means values never expire.
+	ExpireAfter time.Duration
+	// ErrorTTL is how long an error from the loader is returned by Get before trying to load again.
+	// Zero means errors are not cached.
+	ErrorTTL time.Duration
+	// Now returns the current time. If nil, uses time.Now.
+	Now func() time.Time
+}
+
+// LoadingCache is a cache that fills itself using a loader function.
+//
+// Concurrent calls to Get for the same key share a single call to the loader. Loads happen in the
+// background, so that a caller of Get can give up waiting when its context expires without
+// affecting other callers waiting for the same key. Once every caller waiting for a load has given
+// up, the load is cancelled.
+//
+// Entries are evicted in least-recently-used order once the cache holds capacity entries.
+//
+// LoadingCache is safe for concurrent use by multiple goroutines.
+type LoadingCache[K comparable, V any] struct {
+	loader func(context.Context, K) (V, error)
+	opts   LoadingOptions
+	group  *xsync.Group
+
+	m      sync.Mutex
+	c      *lru.Cache[K, *loadingEntry[V]]
+	closed bool
+}
+
+type loadingEntry[V any] struct {
+	// Whether v or err is set.
+	loaded   bool
+	v        V
+	err      error
+	loadedAt time.Time
+	// When refreshing v last failed, or the zero time if it hasn't since v was loaded.
+	refreshErrAt time.Time
+	// Non-nil while a load is in progress.
+	inflight *load[V]
+}
+
+type load[V any] struct {
+	result *xsync.Future[loadResult[V]]
+	// The number of calls to Get waiting for result.
+	waiters int
+	// Cancels the context passed to the loader. Nil until the loader has started.
+	cancel context.CancelFunc
+	// Set when every waiter has given up, after which the result is not cached.
+	abandoned bool
+}
+
+type loadResult[V any] struct {
+	v   V
+	err error
+}
+
+// NewLoadingCache returns a LoadingCache that holds at most capacity entries and uses loader to
+// fill them. The context passed to loader is cancelled when the LoadingCache is closed, or when
+// every Get waiting for the load has given up.
+func NewLoadingCache[K comparable, V any](
+	loader func(ctx context.Context, k K) (V, error),
+	capacity int,
+	opts LoadingOptions,
+) *LoadingCache[K, V] {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &LoadingCache[K, V]{
+		loader: loader,
+		opts:   opts,
+		group:  xsync.NewGroup(context.Background()),
+		c:      lru.New(capacity, lru.Options[K, *loadingEntry[V]]{}),
+	}
+}
+
+// Get returns the value for k, loading it if necessary.
+//
+// If the cached value for k is older than RefreshAfter, Get returns it and starts loading a new one
+// in the background. If there is no cached value, or it is older than ExpireAfter, Get waits for a
+// load to finish, or for ctx to expire in which case it returns ctx.Err().
+//
+// If the loader returns an error, Get returns the same error for ErrorTTL without trying to load
+// again. If refreshing a value fails, Get keeps returning the stale value until it expires, and
+// doesn't try to refresh it again for ErrorTTL.
+func (c *LoadingCache[K, V]) Get(ctx context.Context, k K) (V, error) {
+	var zero V
+
+	c.m.Lock()
+	e, ok := c.c.Get(k)
+	if ok && e.loaded {
+		now := c.opts.Now()
+		age := now.Sub(e.loadedAt)
+		if e.err != nil {
+			if age < c.opts.ErrorTTL {
+				err := e.err
+				c.m.Unlock()
+				return zero, err
+			}
+		} else if c.opts.ExpireAfter == 0 || age < c.opts.ExpireAfter {
+			refreshDue := c.opts.RefreshAfter != 0 && age >= c.opts.RefreshAfter &&
+				(e.refreshErrAt.IsZero() || now.Sub(e.refreshErrAt) >= c.opts.ErrorTTL)
+			if refreshDue && e.inflight == nil && !c.closed {
+				c.startLoad(k, e)
+			}
+			v := e.v
+			c.m.Unlock()
+			return v, nil
+		}
+	}
+	if !ok {
+		e = &loadingEntry[V]{}
+		c.c.Put(k, e)
+	}
+	if e.inflight == nil {
+		if c.closed {
+			c.m.Unlock()
+			return zero, ErrClosed
+		}
+		c.startLoad(k, e)
+	}
+	ld := e.inflight
+	ld.waiters++
+	c.m.Unlock()
+
+	result, err := ld.result.WaitContext(ctx)
+	if err != nil {
+		c.m.Lock()
+		ld.waiters--
+		if ld.waiters == 0 && e.inflight == ld {
+			c.abandonLoad(k, e)
+		}
+		c.m.Unlock()
+		return zero, err
+	}
+	return result.v, result.err
+}
+
+// Invalidate removes any cached value or error for k, so that the next Get loads it again.
+func (c *LoadingCache[K, V]) Invalidate(k K) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.c.Remove(k)
+}
+
+// Len returns the number of keys in the cache, including those that are currently loading.
+func (c *LoadingCache[K, V]) Len() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.c.Len()
+}
+
+// Close cancels the context passed to any in-progress loads and waits for them to finish. After
+// Close, Get continues to return cached values but returns ErrClosed instead of loading.
+func (c *LoadingCache[K, V]) Close() {
+	c.m.Lock()
+	c.closed = true
+	c.m.Unlock()
+	c.group.StopAndWait()
+}
+
+// startLoad must be called with c.m held.
+func (c *LoadingCache[K, V]) startLoad(k K, e *loadingEntry[V]) {
+	ld := &load[V]{result: xsync.NewFuture[loadResult[V]]()}
+	e.inflight = ld
+	c.group.Do(func(ctx context.Context) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		c.m.Lock()
+		if ld.abandoned {
+			c.m.Unlock()
+			return
+		}
+		ld.cancel = cancel
+		c.m.Unlock()
+
+		v, err := c.loader(ctx, k)
+
+		c.m.Lock()
+		if ld.abandoned {
+			// Nobody is waiting for the result, and the error is probably only the cancellation.
+			c.m.Unlock()
+			return
+		}
+		e.inflight = nil
+		if err == nil || !e.loaded || e.err != nil {
+			e.loaded = true
+			e.v = v
+			e.err = err
+			e.loadedAt = c.opts.Now()
+			e.refreshErrAt = time.Time{}
+		} else {
+			// A refresh failed, so keep the stale value but hold off on refreshing it again.
+			e.refreshErrAt = c.opts.Now()
+		}
+		// If e was evicted or invalidated while loading, just deliver the result to the waiters
+		// without caching it.
+		current, ok := c.c.Peek(k)
+		if ok && current == e {
+			if e.err != nil && c.opts.ErrorTTL == 0 {
+				c.c.Remove(k)
+			} else {
+				// Mark as recently used.
+				c.c.Put(k, e)
+			}
+		}
+		c.m.Unlock()
+
+		ld.result.Fill(loadResult[V]{v: v, err: err})
+	})
+}
+
+// abandonLoad cancels e's in-progress load, which no Get is waiting for anymore, so that the next
+// Get starts a new one. Must be called with c.m held.
+func (c *LoadingCache[K, V]) abandonLoad(k K, e *loadingEntry[V]) {
+	ld := e.inflight
+	ld.abandoned = true
+	if ld.cancel != nil {
+		ld.cancel()
+	}
+	e.inflight = nil
+	// There's nothing to keep for k if it was never loaded.
+	current, ok := c.c.Peek(k)
+	if !e.loaded && ok && current == e {
+		c.c.Remove(k)
+	}
+}
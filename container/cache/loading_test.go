@@ -0,0 +1,305 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bradenaw/juniper/internal/require2"
+)
+
+func TestLoadingCacheDeduplicates(t *testing.T) {
+	ctx := context.Background()
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	c := NewLoadingCache(func(ctx context.Context, k string) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		close(started)
+		<-release
+		return len(k), nil
+	}, 10, LoadingOptions{})
+	defer c.Close()
+
+	first := make(chan int)
+	go func() {
+		v, _ := c.Get(ctx, "foo")
+		first <- v
+	}()
+	<-started
+
+	// The load is blocked, so these have to join it rather than find a cached value. Their contexts
+	// are already cancelled, so they give up right away, but the load continues for the first Get.
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	for i := 0; i < 5; i++ {
+		_, err := c.Get(cancelledCtx, "foo")
+		require2.ErrorIs(t, err, context.Canceled)
+	}
+	require2.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	var wg sync.WaitGroup
+	results := make([]int, 10)
+	for i := range results {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = c.Get(ctx, "foo")
+		}()
+	}
+	close(release)
+	require2.Equal(t, 3, <-first)
+	wg.Wait()
+
+	for _, result := range results {
+		require2.Equal(t, 3, result)
+	}
+	require2.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestLoadingCacheRefreshAndExpire(t *testing.T) {
+	ctx := context.Background()
+	var m sync.Mutex
+	now := time.Unix(1000, 0)
+	var calls int32
+	// Each load returns the next value sent on values.
+	values := make(chan int, 1)
+	c := NewLoadingCache(func(ctx context.Context, k string) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return <-values, nil
+	}, 10, LoadingOptions{
+		RefreshAfter: time.Minute,
+		ExpireAfter:  time.Hour,
+		Now: func() time.Time {
+			m.Lock()
+			defer m.Unlock()
+			return now
+		},
+	})
+	defer c.Close()
+	advance := func(d time.Duration) {
+		m.Lock()
+		now = now.Add(d)
+		m.Unlock()
+	}
+
+	values <- 1
+	v, err := c.Get(ctx, "a")
+	require2.NoError(t, err)
+	require2.Equal(t, 1, v)
+
+	// Fresh, so returned without loading.
+	advance(30 * time.Second)
+	v, err = c.Get(ctx, "a")
+	require2.NoError(t, err)
+	require2.Equal(t, 1, v)
+	require2.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Stale, so returns the old value but starts a refresh, which blocks until a value is sent.
+	advance(time.Minute)
+	v, err = c.Get(ctx, "a")
+	require2.NoError(t, err)
+	require2.Equal(t, 1, v)
+
+	// Expired, so waits for the refresh rather than starting another load. Whether the refresh
+	// finishes before or after this Get starts, it gets the refreshed value.
+	advance(2 * time.Hour)
+	values <- 2
+	v, err = c.Get(ctx, "a")
+	require2.NoError(t, err)
+	require2.Equal(t, 2, v)
+	require2.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// Expired with no refresh in progress, so waits for a new load.
+	advance(2 * time.Hour)
+	values <- 3
+	v, err = c.Get(ctx, "a")
+	require2.NoError(t, err)
+	require2.Equal(t, 3, v)
+	require2.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestLoadingCacheRefreshError(t *testing.T) {
+	ctx := context.Background()
+	errBoom := errors.New("boom")
+	var m sync.Mutex
+	now := time.Unix(1000, 0)
+	// If non-nil, closed on the next call to Now.
+	var nowCalled chan struct{}
+	var calls int32
+	// Every load after the first returns the error sent on errs, or 2 if it's nil.
+	errs := make(chan error)
+	c := NewLoadingCache(func(ctx context.Context, k string) (int, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return 1, nil
+		}
+		err := <-errs
+		return 2, err
+	}, 10, LoadingOptions{
+		RefreshAfter: time.Minute,
+		ErrorTTL:     time.Minute,
+		Now: func() time.Time {
+			m.Lock()
+			defer m.Unlock()
+			if nowCalled != nil {
+				close(nowCalled)
+				nowCalled = nil
+			}
+			return now
+		},
+	})
+	defer c.Close()
+	advance := func(d time.Duration) {
+		m.Lock()
+		now = now.Add(d)
+		m.Unlock()
+	}
+	refreshing := func() bool {
+		c.m.Lock()
+		defer c.m.Unlock()
+		e, ok := c.c.Peek("a")
+		return ok && e.inflight != nil
+	}
+	// Finishes the in-progress refresh with err, and waits for the result to be stored. Nothing else
+	// calls Now meanwhile, and the refresh calls it while holding c.m to record the result.
+	finishRefresh := func(err error) {
+		stored := make(chan struct{})
+		m.Lock()
+		nowCalled = stored
+		m.Unlock()
+		errs <- err
+		<-stored
+		require2.True(t, !refreshing())
+	}
+
+	v, err := c.Get(ctx, "a")
+	require2.NoError(t, err)
+	require2.Equal(t, 1, v)
+
+	advance(2 * time.Minute)
+	v, err = c.Get(ctx, "a")
+	require2.NoError(t, err)
+	require2.Equal(t, 1, v)
+	require2.True(t, refreshing())
+	finishRefresh(errBoom)
+
+	// The refresh failed, so the stale value is still returned and no refresh is started until
+	// ErrorTTL has passed.
+	for i := 0; i < 100; i++ {
+		v, err = c.Get(ctx, "a")
+		require2.NoError(t, err)
+		require2.Equal(t, 1, v)
+		require2.True(t, !refreshing())
+	}
+	advance(59 * time.Second)
+	v, _ = c.Get(ctx, "a")
+	require2.Equal(t, 1, v)
+	require2.True(t, !refreshing())
+	require2.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	advance(time.Second)
+	v, _ = c.Get(ctx, "a")
+	require2.Equal(t, 1, v)
+	require2.True(t, refreshing())
+	finishRefresh(nil)
+	v, _ = c.Get(ctx, "a")
+	require2.Equal(t, 2, v)
+	require2.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestLoadingCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	errBoom := errors.New("boom")
+	var calls int32
+	c := NewLoadingCache(func(ctx context.Context, k string) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return 0, errBoom
+	}, 10, LoadingOptions{
+		ErrorTTL: time.Minute,
+		Now:      func() time.Time { return now },
+	})
+	defer c.Close()
+
+	_, err := c.Get(ctx, "a")
+	require2.ErrorIs(t, err, errBoom)
+	_, err = c.Get(ctx, "a")
+	require2.ErrorIs(t, err, errBoom)
+	require2.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	now = now.Add(time.Minute)
+	_, err = c.Get(ctx, "a")
+	require2.ErrorIs(t, err, errBoom)
+	require2.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	c.Invalidate("a")
+	_, err = c.Get(ctx, "a")
+	require2.ErrorIs(t, err, errBoom)
+	require2.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestLoadingCacheWaiterCancellation(t *testing.T) {
+	started := make(chan struct{}, 1)
+	cancelled := make(chan struct{}, 1)
+	values := make(chan int, 1)
+	c := NewLoadingCache(func(ctx context.Context, k string) (int, error) {
+		started <- struct{}{}
+		select {
+		case <-ctx.Done():
+			cancelled <- struct{}{}
+			return 0, ctx.Err()
+		case v := <-values:
+			return v, nil
+		}
+	}, 10, LoadingOptions{})
+	defer c.Close()
+
+	// The only waiter gives up, so the load is cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	_, err := c.Get(ctx, "a")
+	require2.ErrorIs(t, err, context.Canceled)
+	<-cancelled
+	require2.Equal(t, 0, c.Len())
+
+	// The cancellation isn't cached, and the next Get starts a new load. One waiter giving up
+	// doesn't affect the load while another is still waiting.
+	result := make(chan int)
+	go func() {
+		v, _ := c.Get(context.Background(), "a")
+		result <- v
+	}()
+	<-started
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = c.Get(ctx, "a")
+	require2.ErrorIs(t, err, context.Canceled)
+	values <- 5
+	require2.Equal(t, 5, <-result)
+	require2.Equal(t, 0, len(cancelled))
+}
+
+func TestLoadingCacheClose(t *testing.T) {
+	ctx := context.Background()
+	c := NewLoadingCache(func(ctx context.Context, k string) (int, error) {
+		return 1, nil
+	}, 10, LoadingOptions{})
+
+	v, err := c.Get(ctx, "a")
+	require2.NoError(t, err)
+	require2.Equal(t, 1, v)
+
+	c.Close()
+	v, err = c.Get(ctx, "a")
+	require2.NoError(t, err)
+	require2.Equal(t, 1, v)
+	_, err = c.Get(ctx, "b")
+	require2.ErrorIs(t, err, ErrClosed)
+}
@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"math/bits"
+
+	"github.com/bradenaw/juniper/container/sketch"
+	"github.com/bradenaw/juniper/container/xlist"
+)
+
+type tinyLFUSegment int
+
+const (
+	segmentWindow tinyLFUSegment = iota
+	segmentProbation
+	segmentProtected
+)
+
+// TinyLFU is a cache using the W-TinyLFU policy (https://arxiv.org/abs/1512.00727).
+//
+// New entries are admitted into a small least-recently-used window. When an entry is evicted from
+// the window, it only enters the main part of the cache if it has been accessed more often than
+// the entry it would replace, according to an approximate frequency sketch that periodically
+// decays. The main part of the cache is a segmented LRU, which protects entries that have been
+// accessed more than once from entries that have been accessed only once.
+//
+// hash is used to index into the frequency sketch, and should be a good-quality hash function of
+// the key.
+//
+// Get, Peek, Put, and Remove take O(1) time.
+//
+// TinyLFU is not safe for concurrent use.
+type TinyLFU[K comparable, V any] struct {
+	hash         func(K) uint64
+	windowCap    int
+	mainCap      int
+	protectedCap int
+
+	// In each of these, front is least-recently used.
+	window    xlist.List[tinyLFUEntry[K, V]]
+	probation xlist.List[tinyLFUEntry[K, V]]
+	protected xlist.List[tinyLFUEntry[K, V]]
+
+	m map[K]*xlist.Node[tinyLFUEntry[K, V]]
+	// Approximately how many times each key's hash has been accessed. Once the total reaches
+	// sampleSize, every count is halved so that the sketch favors recent history.
+	frequency  *sketch.CountMin[uint64]
+	sampleSize uint64
+}
+
+type tinyLFUEntry[K any, V any] struct {
+	k       K
+	v       V
+	hash    uint64
+	segment tinyLFUSegment
+}
+
+// NewTinyLFU returns an empty TinyLFU that holds at most capacity entries.
+func NewTinyLFU[K comparable, V any](capacity int, hash func(K) uint64) *TinyLFU[K, V] {
+	windowCap := capacity / 100
+	if windowCap < 1 && capacity > 0 {
+		windowCap = 1
+	}
+	mainCap := capacity - windowCap
+	if mainCap < 0 {
+		mainCap = 0
+	}
+	width := 16
+	if capacity > width {
+		width = 1 << bits.Len(uint(capacity-1))
+	}
+	return &TinyLFU[K, V]{
+		hash:         hash,
+		windowCap:    windowCap,
+		mainCap:      mainCap,
+		protectedCap: mainCap * 8 / 10,
+		m:            make(map[K]*xlist.Node[tinyLFUEntry[K, V]]),
+		frequency:    sketch.NewCountMinSize(width, 4, func(h uint64) uint64 { return h }),
+		sampleSize:   10 * uint64(width),
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (c *TinyLFU[K, V]) Len() int {
+	return len(c.m)
+}
+
+// Get returns the value for k if it is present, otherwise returns false in the second return.
+func (c *TinyLFU[K, V]) Get(k K) (V, bool) {
+	node, ok := c.m[k]
+	if !ok {
+		c.recordAccess(c.hash(k))
+		var zero V
+		return zero, false
+	}
+	c.recordAccess(node.Value.hash)
+	c.touch(node)
+	return node.Value.v, true
+}
+
+// Peek returns the value for k if it is present without counting as an access of k.
+func (c *TinyLFU[K, V]) Peek(k K) (V, bool) {
+	node, ok := c.m[k]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return node.Value.v, true
+}
+
+// Put adds an entry for k with value v, replacing any existing entry.
+func (c *TinyLFU[K, V]) Put(k K, v V) {
+	node, ok := c.m[k]
+	if ok {
+		c.recordAccess(node.Value.hash)
+		node.Value.v = v
+		c.touch(node)
+		return
+	}
+	h := c.hash(k)
+	c.recordAccess(h)
+	if c.windowCap+c.mainCap <= 0 {
+		return
+	}
+	c.m[k] = c.window.PushBack(tinyLFUEntry[K, V]{
+		k:       k,
+		v:       v,
+		hash:    h,
+		segment: segmentWindow,
+	})
+	if c.window.Len() <= c.windowCap {
+		return
+	}
+
+	// The window is over capacity, so its least-recently used entry is a candidate to move into
+	// the main part of the cache.
+	candidate := c.window.Front()
+	if c.probation.Len()+c.protected.Len() < c.mainCap {
+		c.move(candidate, segmentProbation)
+		return
+	}
+	victim := c.probation.Front()
+	if victim == nil {
+		victim = c.protected.Front()
+	}
+	if victim != nil &&
+		c.frequency.Estimate(candidate.Value.hash) > c.frequency.Estimate(victim.Value.hash) {
+		c.discard(victim)
+		c.move(candidate, segmentProbation)
+	} else {
+		c.discard(candidate)
+	}
+}
+
+// Remove removes the entry for k, if present. Returns true if an entry was removed.
+func (c *TinyLFU[K, V]) Remove(k K) bool {
+	node, ok := c.m[k]
+	if !ok {
+		return false
+	}
+	c.discard(node)
+	return true
+}
+
+// touch handles an access of a resident entry.
+func (c *TinyLFU[K, V]) touch(node *xlist.Node[tinyLFUEntry[K, V]]) {
+	switch node.Value.segment {
+	case segmentWindow:
+		c.window.MoveToBack(node)
+	case segmentProbation:
+		c.move(node, segmentProtected)
+		if c.protected.Len() > c.protectedCap {
+			c.move(c.protected.Front(), segmentProbation)
+		}
+	case segmentProtected:
+		c.protected.MoveToBack(node)
+	}
+}
+
+func (c *TinyLFU[K, V]) list(segment tinyLFUSegment) *xlist.List[tinyLFUEntry[K, V]] {
+	switch segment {
+	case segmentWindow:
+		return &c.window
+	case segmentProbation:
+		return &c.probation
+	default:
+		return &c.protected
+	}
+}
+
+// move moves node to the most-recently used position of segment.
+func (c *TinyLFU[K, V]) move(node *xlist.Node[tinyLFUEntry[K, V]], segment tinyLFUSegment) {
+	c.list(node.Value.segment).MoveToList(node, c.list(segment))
+	node.Value.segment = segment
+}
+
+func (c *TinyLFU[K, V]) discard(node *xlist.Node[tinyLFUEntry[K, V]]) {
+	c.list(node.Value.segment).Remove(node)
+	delete(c.m, node.Value.k)
+}
+
+// recordAccess counts an access of the key with hash h.
+func (c *TinyLFU[K, V]) recordAccess(h uint64) {
+	c.frequency.Add(h, 1)
+	if c.frequency.Total() >= c.sampleSize {
+		c.frequency.Halve()
+	}
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"github.com/bradenaw/juniper/container/xlist"
+)
+
+// TwoQueue is a cache using the full version of the 2Q policy
+// (https://www.vldb.org/conf/1994/P439.PDF).
+//
+// Newly-added entries go into a small first-in-first-out queue. If they are evicted from there, the
+// cache remembers their keys for a while without their values. Only entries that are added again
+// while remembered are promoted to the main least-recently-used queue, so keys that are only seen
+// once cannot evict frequently-used entries.
+//
+// Get, Peek, Put, and Remove take O(1) time.
+//
+// TwoQueue is not safe for concurrent use.
+type TwoQueue[K comparable, V any] struct {
+	capacity int
+	// Maximum length of in.
+	inCap int
+	// Maximum length of out.
+	outCap int
+
+	// Entries seen for the first time, first-in-first-out. Front is oldest.
+	in xlist.List[twoQueueEntry[K, V]]
+	// Keys recently evicted from in. Front is oldest.
+	out xlist.List[K]
+	// Entries seen again while in out, least-recently used. Front is least-recently used.
+	main xlist.List[twoQueueEntry[K, V]]
+
+	// Entries in either in or main.
+	m     map[K]*xlist.Node[twoQueueEntry[K, V]]
+	ghost map[K]*xlist.Node[K]
+}
+
+type twoQueueEntry[K any, V any] struct {
+	k      K
+	v      V
+	inMain bool
+}
+
+// NewTwoQueue returns an empty TwoQueue that holds at most capacity entries.
+func NewTwoQueue[K comparable, V any](capacity int) *TwoQueue[K, V] {
+	inCap := capacity / 4
+	if inCap < 1 {
+		inCap = 1
+	}
+	outCap := capacity / 2
+	if outCap < 1 {
+		outCap = 1
+	}
+	return &TwoQueue[K, V]{
+		capacity: capacity,
+		inCap:    inCap,
+		outCap:   outCap,
+		m:        make(map[K]*xlist.Node[twoQueueEntry[K, V]]),
+		ghost:    make(map[K]*xlist.Node[K]),
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (c *TwoQueue[K, V]) Len() int {
+	return len(c.m)
+}
+
+// Get returns the value for k if it is present, otherwise returns false in the second return.
+func (c *TwoQueue[K, V]) Get(k K) (V, bool) {
+	node, ok := c.m[k]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	if node.Value.inMain {
+		c.main.MoveToBack(node)
+	}
+	return node.Value.v, true
+}
+
+// Peek returns the value for k if it is present without counting as an access of k.
+func (c *TwoQueue[K, V]) Peek(k K) (V, bool) {
+	node, ok := c.m[k]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return node.Value.v, true
+}
+
+// Put adds an entry for k with value v, replacing any existing entry.
+func (c *TwoQueue[K, V]) Put(k K, v V) {
+	node, ok := c.m[k]
+	if ok {
+		node.Value.v = v
+		if node.Value.inMain {
+			c.main.MoveToBack(node)
+		}
+		return
+	}
+	if c.capacity <= 0 {
+		return
+	}
+
+	if len(c.m) >= c.capacity {
+		c.reclaim()
+	}
+
+	ghostNode, ok := c.ghost[k]
+	if ok {
+		c.out.Remove(ghostNode)
+		delete(c.ghost, k)
+		c.m[k] = c.main.PushBack(twoQueueEntry[K, V]{k: k, v: v, inMain: true})
+	} else {
+		c.m[k] = c.in.PushBack(twoQueueEntry[K, V]{k: k, v: v})
+	}
+}
+
+// Remove removes the entry for k, if present. Returns true if an entry was removed.
+func (c *TwoQueue[K, V]) Remove(k K) bool {
+	node, ok := c.m[k]
+	if !ok {
+		return false
+	}
+	if node.Value.inMain {
+		c.main.Remove(node)
+	} else {
+		c.in.Remove(node)
+	}
+	delete(c.m, k)
+	return true
+}
+
+// reclaim evicts one entry to make room for another.
+func (c *TwoQueue[K, V]) reclaim() {
+	if c.in.Len() >= c.inCap || c.main.Len() == 0 {
+		node := c.in.Front()
+		c.in.Remove(node)
+		delete(c.m, node.Value.k)
+		c.ghost[node.Value.k] = c.out.PushBack(node.Value.k)
+		if c.out.Len() > c.outCap {
+			ghostNode := c.out.Front()
+			c.out.Remove(ghostNode)
+			delete(c.ghost, ghostNode.Value)
+		}
+	} else {
+		node := c.main.Front()
+		c.main.Remove(node)
+		delete(c.m, node.Value.k)
+	}
+}
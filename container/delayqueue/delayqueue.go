@@ -0,0 +1,185 @@
+// Package delayqueue contains a queue whose items become available at a scheduled time.
+package delayqueue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bradenaw/juniper/container/xheap"
+)
+
+// Options configures a DelayQueue. The zero value is a valid configuration.
+type Options struct {
+	// Now returns the current time. If nil, uses time.Now.
+	Now func() time.Time
+	// AfterFunc waits for d and then calls f in its own goroutine, and returns a function that
+	// prevents f from being called if it hasn't been already. If nil, uses time.AfterFunc.
+	AfterFunc func(d time.Duration, f func()) (stop func() bool)
+}
+
+// Handle refers to an item that was pushed into a DelayQueue, and can be used to cancel or
+// reschedule it.
+type Handle struct {
+	id uint64
+}
+
+// DelayQueue is a queue of items that are each released at a given time. Pop blocks until the
+// item with the earliest ready time is due. Items with the same ready time are released in the
+// order they were pushed, or rescheduled to that time.
+//
+// DelayQueue is safe for concurrent use by multiple goroutines.
+type DelayQueue[T any] struct {
+	now       func() time.Time
+	afterFunc func(d time.Duration, f func()) (stop func() bool)
+
+	m      sync.Mutex
+	pq     xheap.PriorityQueue[uint64, time.Time]
+	items  map[uint64]T
+	nextID uint64
+	// Closed and replaced whenever the earliest ready time may have changed, to wake up waiting
+	// Pops.
+	changed chan struct{}
+}
+
+// New returns an empty DelayQueue.
+func New[T any](opts Options) *DelayQueue[T] {
+	q := &DelayQueue[T]{
+		now:       opts.Now,
+		afterFunc: opts.AfterFunc,
+		pq: xheap.NewStablePriorityQueue[uint64](
+			func(a, b time.Time) bool { return a.Before(b) },
+			nil,
+		),
+		items:   make(map[uint64]T),
+		changed: make(chan struct{}),
+	}
+	if q.now == nil {
+		q.now = time.Now
+	}
+	if q.afterFunc == nil {
+		q.afterFunc = func(d time.Duration, f func()) func() bool {
+			return time.AfterFunc(d, f).Stop
+		}
+	}
+	return q
+}
+
+// Len returns the number of items in the queue, including those that are not yet ready.
+func (q *DelayQueue[T]) Len() int {
+	q.m.Lock()
+	defer q.m.Unlock()
+	return q.pq.Len()
+}
+
+// Push adds item to the queue to be released at readyAt. The returned Handle can be passed to
+// Cancel and Reschedule.
+func (q *DelayQueue[T]) Push(item T, readyAt time.Time) Handle {
+	q.m.Lock()
+	defer q.m.Unlock()
+	q.nextID++
+	id := q.nextID
+	q.items[id] = item
+	q.pq.Update(id, readyAt)
+	if q.pq.Peek() == id {
+		q.notify()
+	}
+	return Handle{id: id}
+}
+
+// Cancel removes the item referred to by h from the queue. It returns false if the item is no
+// longer in the queue, because it was already popped or cancelled.
+func (q *DelayQueue[T]) Cancel(h Handle) bool {
+	q.m.Lock()
+	defer q.m.Unlock()
+	if !q.pq.Contains(h.id) {
+		return false
+	}
+	q.pq.Remove(h.id)
+	delete(q.items, h.id)
+	q.notify()
+	return true
+}
+
+// Reschedule changes the time that the item referred to by h will be released to readyAt. It
+// returns false if the item is no longer in the queue, because it was already popped or cancelled.
+func (q *DelayQueue[T]) Reschedule(h Handle, readyAt time.Time) bool {
+	q.m.Lock()
+	defer q.m.Unlock()
+	if !q.pq.Contains(h.id) {
+		return false
+	}
+	q.pq.Update(h.id, readyAt)
+	q.notify()
+	return true
+}
+
+// TryPop removes and returns the item with the earliest ready time if it is due. Otherwise, it
+// returns false in the second return without blocking.
+func (q *DelayQueue[T]) TryPop() (T, bool) {
+	q.m.Lock()
+	defer q.m.Unlock()
+	item, _, ok := q.tryPopLocked()
+	return item, ok
+}
+
+// Pop removes and returns the item with the earliest ready time, blocking until that time if
+// necessary. If an item with an earlier ready time is pushed while waiting, Pop wakes up to wait
+// for it instead.
+//
+// If ctx expires before an item is ready, Pop returns ctx.Err().
+func (q *DelayQueue[T]) Pop(ctx context.Context) (T, error) {
+	var zero T
+	for {
+		q.m.Lock()
+		item, wait, ok := q.tryPopLocked()
+		changed := q.changed
+		q.m.Unlock()
+		if ok {
+			return item, nil
+		}
+
+		var ready chan struct{}
+		var stop func() bool
+		if wait > 0 {
+			ready = make(chan struct{})
+			stop = q.afterFunc(wait, func() { close(ready) })
+		}
+
+		select {
+		case <-ctx.Done():
+			if stop != nil {
+				stop()
+			}
+			return zero, ctx.Err()
+		case <-changed:
+		case <-ready:
+		}
+		if stop != nil {
+			stop()
+		}
+	}
+}
+
+// tryPopLocked pops the earliest item if it is ready. If not, returns how long until it will be
+// ready, or 0 if the queue is empty.
+func (q *DelayQueue[T]) tryPopLocked() (T, time.Duration, bool) {
+	var zero T
+	if q.pq.Len() == 0 {
+		return zero, 0, false
+	}
+	id := q.pq.Peek()
+	wait := q.pq.Priority(id).Sub(q.now())
+	if wait > 0 {
+		return zero, wait, false
+	}
+	q.pq.Pop()
+	item := q.items[id]
+	delete(q.items, id)
+	return item, 0, true
+}
+
+func (q *DelayQueue[T]) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
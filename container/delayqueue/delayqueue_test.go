@@ -0,0 +1,185 @@
+package delayqueue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bradenaw/juniper/internal/require2"
+)
+
+type fakeClock struct {
+	m      sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	// Receives every time a timer is created, so tests can wait for Pop to block.
+	created chan struct{}
+}
+
+type fakeTimer struct {
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:     time.Unix(1000, 0),
+		created: make(chan struct{}, 100),
+	}
+}
+
+func (c *fakeClock) Options() Options {
+	return Options{Now: c.Now, AfterFunc: c.AfterFunc}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+	t := &fakeTimer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	c.created <- struct{}{}
+	return func() bool {
+		c.m.Lock()
+		defer c.m.Unlock()
+		wasStopped := t.stopped
+		t.stopped = true
+		return !wasStopped
+	}
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.m.Lock()
+	c.now = c.now.Add(d)
+	var due []func()
+	remaining := c.timers[:0]
+	for _, t := range c.timers {
+		if t.stopped {
+			continue
+		}
+		if !t.at.After(c.now) {
+			t.stopped = true
+			due = append(due, t.f)
+			continue
+		}
+		remaining = append(remaining, t)
+	}
+	c.timers = remaining
+	c.m.Unlock()
+
+	for _, f := range due {
+		go f()
+	}
+}
+
+func TestDelayQueue(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	q := New[string](clock.Options())
+
+	start := clock.Now()
+	q.Push("c", start.Add(3*time.Second))
+	a := q.Push("a", start.Add(1*time.Second))
+	b := q.Push("b", start.Add(2*time.Second))
+	require2.Equal(t, 3, q.Len())
+
+	_, ok := q.TryPop()
+	require2.True(t, !ok)
+
+	clock.Advance(time.Second)
+	item, ok := q.TryPop()
+	require2.True(t, ok)
+	require2.Equal(t, "a", item)
+	require2.True(t, !q.Cancel(a))
+	require2.True(t, !q.Reschedule(a, start))
+
+	require2.True(t, q.Reschedule(b, start.Add(4*time.Second)))
+	clock.Advance(2 * time.Second)
+	item, err := q.Pop(ctx)
+	require2.NoError(t, err)
+	require2.Equal(t, "c", item)
+
+	require2.True(t, q.Cancel(b))
+	require2.True(t, !q.Cancel(b))
+	require2.Equal(t, 0, q.Len())
+}
+
+func TestDelayQueueTies(t *testing.T) {
+	clock := newFakeClock()
+	q := New[int](clock.Options())
+	at := clock.Now().Add(time.Second)
+
+	handles := make([]Handle, 0, 10)
+	for i := 0; i < 10; i++ {
+		handles = append(handles, q.Push(i, at))
+	}
+	// Rescheduling to the same time moves an item behind the others with that time.
+	require2.True(t, q.Reschedule(handles[3], at))
+
+	clock.Advance(time.Second)
+	var popped []int
+	for {
+		item, ok := q.TryPop()
+		if !ok {
+			break
+		}
+		popped = append(popped, item)
+	}
+	require2.SlicesEqual(t, []int{0, 1, 2, 4, 5, 6, 7, 8, 9, 3}, popped)
+}
+
+func TestDelayQueuePopWaits(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	q := New[string](clock.Options())
+	start := clock.Now()
+
+	result := make(chan string, 1)
+	go func() {
+		item, _ := q.Pop(ctx)
+		result <- item
+	}()
+
+	// Pop is waiting on an empty queue, pushing should wake it up to wait for the new item.
+	q.Push("later", start.Add(10*time.Second))
+	<-clock.created
+	// Pushing an earlier item should make Pop wait for that one instead.
+	q.Push("sooner", start.Add(5*time.Second))
+	<-clock.created
+
+	clock.Advance(5 * time.Second)
+	require2.Equal(t, "sooner", <-result)
+	require2.Equal(t, 1, q.Len())
+}
+
+func TestDelayQueuePopContext(t *testing.T) {
+	clock := newFakeClock()
+	q := New[int](clock.Options())
+	q.Push(1, clock.Now().Add(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-clock.created
+		cancel()
+	}()
+	_, err := q.Pop(ctx)
+	require2.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDelayQueueRealClock(t *testing.T) {
+	ctx := context.Background()
+	q := New[int](Options{})
+	start := time.Now()
+	q.Push(1, start.Add(10*time.Millisecond))
+	item, err := q.Pop(ctx)
+	require2.NoError(t, err)
+	require2.Equal(t, 1, item)
+	require2.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+}
@@ -0,0 +1,165 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/bradenaw/juniper/internal/hash"
+)
+
+// Bloom is a Bloom filter (https://en.wikipedia.org/wiki/Bloom_filter).
+//
+// MayContain never returns false for a key that has been added, but may return true for a key that
+// has not. Keys cannot be removed; see Cuckoo for a filter that supports deleting.
+//
+// Bloom is not safe for concurrent use.
+type Bloom[K any] struct {
+	hash func(K) uint64
+	// The number of bits set for each key.
+	k int
+	// The number of bits, len(bits)*64.
+	m    uint64
+	bits []uint64
+}
+
+// NewBloom returns an empty Bloom filter sized so that after adding n keys, MayContain returns true
+// for keys that have not been added with probability about fpRate.
+//
+// Bloom filters use about 1.44*log2(1/fpRate) bits per key, so about 10 bits per key for a 1%
+// false-positive rate.
+func NewBloom[K any](n int, fpRate float64, hash func(K) uint64) *Bloom[K] {
+	if n < 1 {
+		n = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		panic("bloom filter false-positive rate must be in (0, 1)")
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return newBloom(hash, k, (m+63)/64)
+}
+
+func newBloom[K any](hash func(K) uint64, k int, words uint64) *Bloom[K] {
+	if words < 1 {
+		words = 1
+	}
+	return &Bloom[K]{
+		hash: hash,
+		k:    k,
+		m:    words * 64,
+		bits: make([]uint64, words),
+	}
+}
+
+// Add adds key to the filter.
+func (b *Bloom[K]) Add(key K) {
+	h1, h2 := b.hashes(key)
+	for i := 0; i < b.k; i++ {
+		bit := (h1 + uint64(i)*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// MayContain returns false if key has definitely not been added to the filter, and true if it may
+// have been.
+func (b *Bloom[K]) MayContain(key K) bool {
+	h1, h2 := b.hashes(key)
+	for i := 0; i < b.k; i++ {
+		bit := (h1 + uint64(i)*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hashes returns two hashes of key, which are combined to choose the bits for key as in
+// https://www.eecs.harvard.edu/~michaelm/postscripts/rsa2008.pdf.
+func (b *Bloom[K]) hashes(key K) (uint64, uint64) {
+	h := b.hash(key)
+	return h, hash.Mix(h) | 1
+}
+
+// Union adds all of the keys in other to b. Afterwards, b.MayContain returns true for any key that
+// was added to either filter.
+//
+// b and other must have been created with the same arguments to NewBloom, otherwise Union returns
+// an error.
+func (b *Bloom[K]) Union(other *Bloom[K]) error {
+	if b.k != other.k || b.m != other.m {
+		return errors.New("bloom filters have different sizes")
+	}
+	for i, word := range other.bits {
+		b.bits[i] |= word
+	}
+	return nil
+}
+
+// Reset removes all keys from the filter.
+func (b *Bloom[K]) Reset() {
+	for i := range b.bits {
+		b.bits[i] = 0
+	}
+}
+
+const serialVersion = 1
+
+var errInvalidData = errors.New("invalid filter data")
+
+// MarshalBinary encodes the filter so that it can be decoded by UnmarshalBinary. The hash function
+// is not included.
+func (b *Bloom[K]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(serialVersion)
+	writeUvarint(&buf, uint64(b.k))
+	writeUvarint(&buf, uint64(len(b.bits)))
+	_ = binary.Write(&buf, binary.LittleEndian, b.bits)
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the contents of b with the filter encoded in data by MarshalBinary. The
+// size of b changes to that of the encoded filter.
+//
+// b must use the same hash function as the encoded filter, or MayContain will give wrong answers.
+func (b *Bloom[K]) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	err := readVersion(r)
+	if err != nil {
+		return err
+	}
+	k, err := binary.ReadUvarint(r)
+	if err != nil || k < 1 || k > math.MaxInt32 {
+		return errInvalidData
+	}
+	words, err := binary.ReadUvarint(r)
+	if err != nil || words < 1 || words > uint64(r.Len())/8 ||
+		words*8 != uint64(r.Len()) {
+		return errInvalidData
+	}
+	out := newBloom(b.hash, int(k), words)
+	_ = binary.Read(r, binary.LittleEndian, out.bits)
+	*b = *out
+	return nil
+}
+
+func writeUvarint(w io.Writer, x uint64) {
+	var scratch [binary.MaxVarintLen64]byte
+	_, _ = w.Write(scratch[:binary.PutUvarint(scratch[:], x)])
+}
+
+func readVersion(r io.ByteReader) error {
+	version, err := r.ReadByte()
+	if err != nil {
+		return errInvalidData
+	}
+	if version != serialVersion {
+		return errors.New("unknown filter data version")
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/bits"
+
+	"github.com/bradenaw/juniper/internal/hash"
+)
+
+const (
+	cuckooBucketSize = 4
+	// The maximum number of fingerprints to relocate while adding one key before declaring the
+	// filter full.
+	cuckooMaxKicks = 500
+)
+
+// Cuckoo is a cuckoo filter (https://www.cs.cmu.edu/~dga/papers/cuckoo-conext2014.pdf).
+//
+// Like a Bloom filter, MayContain never returns false for a key that has been added, but may return
+// true for a key that has not. Unlike a Bloom filter, keys can also be deleted.
+//
+// Cuckoo stores a 16-bit fingerprint for each key, for a false-positive rate of about 0.01%. It
+// uses about 17 bits per key at its typical maximum occupancy of 95%.
+//
+// Cuckoo is not safe for concurrent use.
+type Cuckoo[K any] struct {
+	hash func(K) uint64
+	// len(buckets)/cuckooBucketSize - 1. The number of buckets is a power of two.
+	mask uint64
+	// Fingerprints, cuckooBucketSize per bucket. Zero means an empty slot.
+	buckets []uint16
+	count   int
+	// A fingerprint that could not be placed, and one of its two buckets. If victim is non-zero
+	// then the filter is full.
+	victim       uint16
+	victimBucket uint64
+	// State for choosing which fingerprint to relocate.
+	rng uint64
+}
+
+// NewCuckoo returns an empty cuckoo filter with room for about n keys.
+func NewCuckoo[K any](n int, hash func(K) uint64) *Cuckoo[K] {
+	// Aim for 95% occupancy when full.
+	nBuckets := uint64(n)*100/95/cuckooBucketSize + 1
+	nBuckets = 1 << bits.Len64(nBuckets-1)
+	return newCuckoo(hash, nBuckets)
+}
+
+func newCuckoo[K any](hash func(K) uint64, nBuckets uint64) *Cuckoo[K] {
+	return &Cuckoo[K]{
+		hash:    hash,
+		mask:    nBuckets - 1,
+		buckets: make([]uint16, nBuckets*cuckooBucketSize),
+		rng:     1,
+	}
+}
+
+// Count returns the number of keys in the filter.
+func (c *Cuckoo[K]) Count() int {
+	return c.count
+}
+
+// fingerprint returns the fingerprint of key and the two buckets it may be in.
+func (c *Cuckoo[K]) fingerprint(key K) (uint16, uint64, uint64) {
+	h := c.hash(key)
+	fp := uint16(h >> 48)
+	if fp == 0 {
+		fp = 1
+	}
+	i1 := h & c.mask
+	return fp, i1, c.altBucket(i1, fp)
+}
+
+// altBucket returns the other bucket for fp, given one of them. altBucket(altBucket(i, fp), fp) ==
+// i.
+func (c *Cuckoo[K]) altBucket(i uint64, fp uint16) uint64 {
+	return (i ^ hash.Mix(uint64(fp))) & c.mask
+}
+
+func (c *Cuckoo[K]) bucket(i uint64) []uint16 {
+	return c.buckets[i*cuckooBucketSize : (i+1)*cuckooBucketSize]
+}
+
+// Add adds key to the filter. Returns false if the filter is full, in which case key is not added.
+//
+// Adding a key that is already present adds another copy, which uses space. Since all copies of a
+// key share the same two buckets, adding the same key more than 8 times makes the filter full.
+func (c *Cuckoo[K]) Add(key K) bool {
+	if c.victim != 0 {
+		// The last Add couldn't find a place for a fingerprint. Slots may have been freed since then,
+		// so try again.
+		fp, i := c.victim, c.victimBucket
+		c.victim = 0
+		if !c.insert(i, fp) && !c.insert(c.altBucket(i, fp), fp) && !c.kick(i, fp) {
+			return false
+		}
+	}
+	fp, i1, i2 := c.fingerprint(key)
+	if !c.insert(i1, fp) && !c.insert(i2, fp) {
+		i := i1
+		if c.random()%2 == 0 {
+			i = i2
+		}
+		// Even if this fails, some fingerprint is left as the victim so key is still present.
+		c.kick(i, fp)
+	}
+	c.count++
+	return true
+}
+
+// kick places fp in bucket i, which must be full, by moving an existing fingerprint to its other
+// bucket, and so on until one fits. If none fits after cuckooMaxKicks moves, the last one displaced
+// becomes the victim and kick returns false.
+func (c *Cuckoo[K]) kick(i uint64, fp uint16) bool {
+	for n := 0; n < cuckooMaxKicks; n++ {
+		b := c.bucket(i)
+		slot := c.random() % cuckooBucketSize
+		fp, b[slot] = b[slot], fp
+		i = c.altBucket(i, fp)
+		if c.insert(i, fp) {
+			return true
+		}
+	}
+	c.victim = fp
+	c.victimBucket = i
+	return false
+}
+
+func (c *Cuckoo[K]) insert(i uint64, fp uint16) bool {
+	b := c.bucket(i)
+	for j := range b {
+		if b[j] == 0 {
+			b[j] = fp
+			return true
+		}
+	}
+	return false
+}
+
+// MayContain returns false if key has definitely not been added to the filter, and true if it may
+// have been.
+func (c *Cuckoo[K]) MayContain(key K) bool {
+	fp, i1, i2 := c.fingerprint(key)
+	if c.victim == fp && (c.victimBucket == i1 || c.victimBucket == i2) {
+		return true
+	}
+	for _, i := range [2]uint64{i1, i2} {
+		for _, x := range c.bucket(i) {
+			if x == fp {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Delete removes one copy of key from the filter. Returns false if key was definitely not present.
+//
+// Only keys that have been added may be deleted. Deleting a key that was not added may remove a
+// different key that happens to share its fingerprint, causing MayContain to return false for that
+// key.
+func (c *Cuckoo[K]) Delete(key K) bool {
+	fp, i1, i2 := c.fingerprint(key)
+	if c.victim == fp && (c.victimBucket == i1 || c.victimBucket == i2) {
+		c.victim = 0
+		c.count--
+		return true
+	}
+	for _, i := range [2]uint64{i1, i2} {
+		b := c.bucket(i)
+		for j := range b {
+			if b[j] == fp {
+				b[j] = 0
+				c.count--
+				c.placeVictim()
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// placeVictim tries to move the victim back into the table after a slot has been freed.
+func (c *Cuckoo[K]) placeVictim() {
+	if c.victim == 0 {
+		return
+	}
+	if c.insert(c.victimBucket, c.victim) ||
+		c.insert(c.altBucket(c.victimBucket, c.victim), c.victim) {
+		c.victim = 0
+	}
+}
+
+// Reset removes all keys from the filter.
+func (c *Cuckoo[K]) Reset() {
+	for i := range c.buckets {
+		c.buckets[i] = 0
+	}
+	c.count = 0
+	c.victim = 0
+}
+
+func (c *Cuckoo[K]) random() uint64 {
+	// xorshift64
+	c.rng ^= c.rng << 13
+	c.rng ^= c.rng >> 7
+	c.rng ^= c.rng << 17
+	return c.rng
+}
+
+// MarshalBinary encodes the filter so that it can be decoded by UnmarshalBinary. The hash function
+// is not included.
+func (c *Cuckoo[K]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(serialVersion)
+	writeUvarint(&buf, c.mask+1)
+	writeUvarint(&buf, uint64(c.count))
+	writeUvarint(&buf, uint64(c.victim))
+	writeUvarint(&buf, c.victimBucket)
+	_ = binary.Write(&buf, binary.LittleEndian, c.buckets)
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the contents of c with the filter encoded in data by MarshalBinary. The
+// size of c changes to that of the encoded filter.
+//
+// c must use the same hash function as the encoded filter, or MayContain will give wrong answers.
+func (c *Cuckoo[K]) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	err := readVersion(r)
+	if err != nil {
+		return err
+	}
+	var fields [4]uint64
+	for i := range fields {
+		fields[i], err = binary.ReadUvarint(r)
+		if err != nil {
+			return errInvalidData
+		}
+	}
+	nBuckets, count, victim, victimBucket := fields[0], fields[1], fields[2], fields[3]
+	if nBuckets == 0 || nBuckets&(nBuckets-1) != 0 ||
+		nBuckets > uint64(r.Len())/(cuckooBucketSize*2) ||
+		nBuckets*cuckooBucketSize*2 != uint64(r.Len()) ||
+		count > nBuckets*cuckooBucketSize+1 ||
+		victim > 0xFFFF ||
+		victimBucket >= nBuckets {
+		return errInvalidData
+	}
+	out := newCuckoo(c.hash, nBuckets)
+	_ = binary.Read(r, binary.LittleEndian, out.buckets)
+	out.count = int(count)
+	out.victim = uint16(victim)
+	out.victimBucket = victimBucket
+	*c = *out
+	return nil
+}
@@ -0,0 +1,226 @@
+package filter
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/bradenaw/juniper/internal/fuzz"
+	"github.com/bradenaw/juniper/internal/require2"
+)
+
+// falsePositiveRate returns the fraction of keys never added to f for which f.MayContain returns
+// true. The keys added to f must have been in [0, n).
+func falsePositiveRate(mayContain func(int) bool, n int) float64 {
+	const probes = 100000
+	fp := 0
+	for i := n; i < n+probes; i++ {
+		if mayContain(i) {
+			fp++
+		}
+	}
+	return float64(fp) / probes
+}
+
+func TestBloom(t *testing.T) {
+	for _, fpRate := range []float64{0.1, 0.01, 0.001} {
+		t.Run(fmt.Sprint(fpRate), func(t *testing.T) {
+			const n = 10000
+			b := NewBloom(n, fpRate, HashInt[int])
+			for i := 0; i < n; i++ {
+				b.Add(i)
+			}
+			for i := 0; i < n; i++ {
+				require2.True(t, b.MayContain(i))
+			}
+			actual := falsePositiveRate(b.MayContain, n)
+			t.Logf("false-positive rate %.5f, target %.5f", actual, fpRate)
+			require2.Less(t, actual, 1.5*fpRate)
+
+			data, err := b.MarshalBinary()
+			require2.NoError(t, err)
+			decoded := NewBloom(1, 0.5, HashInt[int])
+			require2.NoError(t, decoded.UnmarshalBinary(data))
+			for i := 0; i < n; i++ {
+				require2.True(t, decoded.MayContain(i))
+			}
+			require2.Equal(t, actual, falsePositiveRate(decoded.MayContain, n))
+		})
+	}
+}
+
+func TestBloomUnion(t *testing.T) {
+	a := NewBloom(1000, 0.01, HashString)
+	b := NewBloom(1000, 0.01, HashString)
+	for i := 0; i < 500; i++ {
+		a.Add(strconv.Itoa(i))
+		b.Add(strconv.Itoa(i + 500))
+	}
+	require2.NoError(t, a.Union(b))
+	for i := 0; i < 1000; i++ {
+		require2.True(t, a.MayContain(strconv.Itoa(i)))
+	}
+
+	c := NewBloom(2000, 0.01, HashString)
+	require2.Error(t, a.Union(c))
+}
+
+func TestCuckoo(t *testing.T) {
+	const n = 10000
+	c := NewCuckoo(n, HashInt[int])
+	for i := 0; i < n; i++ {
+		require2.True(t, c.Add(i))
+	}
+	require2.Equal(t, n, c.Count())
+	for i := 0; i < n; i++ {
+		require2.True(t, c.MayContain(i))
+	}
+	actual := falsePositiveRate(c.MayContain, n)
+	t.Logf("false-positive rate %.5f", actual)
+	require2.Less(t, actual, 0.001)
+
+	data, err := c.MarshalBinary()
+	require2.NoError(t, err)
+	decoded := NewCuckoo(1, HashInt[int])
+	require2.NoError(t, decoded.UnmarshalBinary(data))
+	require2.Equal(t, n, decoded.Count())
+
+	for i := 0; i < n; i += 2 {
+		require2.True(t, c.Delete(i))
+	}
+	require2.Equal(t, n/2, c.Count())
+	for i := 1; i < n; i += 2 {
+		require2.True(t, c.MayContain(i))
+	}
+	require2.Less(t, falsePositiveRate(c.MayContain, n), 0.001)
+	// The decoded filter is independent of the original.
+	for i := 0; i < n; i++ {
+		require2.True(t, decoded.MayContain(i))
+	}
+}
+
+func TestCuckooFull(t *testing.T) {
+	c := NewCuckoo(100, HashInt[int])
+	i := 0
+	for c.Add(i) {
+		i++
+	}
+	t.Logf("added %d keys to a filter with %d slots", i, len(c.buckets))
+	require2.Greater(t, i, 100)
+	require2.Equal(t, i, c.Count())
+	for j := 0; j < i; j++ {
+		require2.True(t, c.MayContain(j))
+	}
+
+	// Deleting makes room again.
+	require2.True(t, c.Delete(0))
+	require2.True(t, c.Delete(1))
+	require2.True(t, c.Add(0))
+	for j := 2; j < i; j++ {
+		require2.True(t, c.MayContain(j))
+	}
+}
+
+func FuzzCuckoo(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte) {
+		// Small enough to fill up.
+		c := NewCuckoo(8, HashInt[byte])
+		oracle := make(map[byte]int)
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				n := 0
+				for k, count := range oracle {
+					n += count
+					if count > 0 {
+						require2.True(t, c.MayContain(k))
+					}
+				}
+				require2.Equal(t, n, c.Count())
+			},
+			func(k byte) {
+				t.Logf("Add(%d)", k)
+				if c.Add(k) {
+					oracle[k]++
+				}
+			},
+			func(k byte) {
+				if oracle[k] == 0 {
+					return
+				}
+				t.Logf("Delete(%d)", k)
+				require2.True(t, c.Delete(k))
+				oracle[k]--
+			},
+			func() {
+				t.Logf("MarshalBinary()")
+				data, err := c.MarshalBinary()
+				require2.NoError(t, err)
+				require2.NoError(t, c.UnmarshalBinary(data))
+			},
+		)
+	})
+}
+
+func TestUnmarshalBinaryInvalid(t *testing.T) {
+	header := func(fields ...uint64) []byte {
+		var buf bytes.Buffer
+		buf.WriteByte(serialVersion)
+		for _, x := range fields {
+			writeUvarint(&buf, x)
+		}
+		return buf.Bytes()
+	}
+
+	for _, data := range [][]byte{
+		nil,
+		{serialVersion + 1},
+		header(3),
+		header(3, 2),
+		header(3, 2, 0, 0, 0),
+		// The size of the payload must not overflow when multiplied out.
+		header(3, 1<<61),
+		header(3, 1<<63),
+	} {
+		b := NewBloom(100, 0.01, HashInt[int])
+		require2.Error(t, b.UnmarshalBinary(data))
+	}
+
+	for _, data := range [][]byte{
+		nil,
+		{serialVersion + 1},
+		header(4, 0, 0),
+		header(3, 0, 0, 0),
+		header(4, 0, 0, 0, 0, 0),
+		// The size of the payload must not overflow when multiplied out.
+		header(1<<62, 0, 0, 0),
+		header(1<<63, 0, 0, 0),
+	} {
+		c := NewCuckoo(100, HashInt[int])
+		require2.Error(t, c.UnmarshalBinary(data))
+		// c is unchanged and still usable.
+		c.Add(1)
+		require2.True(t, c.MayContain(1))
+	}
+}
+
+func TestHash(t *testing.T) {
+	require2.Equal(t, HashString("foo"), HashBytes([]byte("foo")))
+	require2.True(t, HashString("foo") != HashString("bar"))
+	require2.True(t, HashInt(1) != HashInt(2))
+}
+
+func ExampleBloom() {
+	b := NewBloom(1000, 0.01, HashString)
+	b.Add("apple")
+	b.Add("banana")
+
+	fmt.Println(b.MayContain("apple"))
+	fmt.Println(b.MayContain("cherry"))
+
+	// Output:
+	// true
+	// false
+}
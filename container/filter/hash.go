@@ -0,0 +1,44 @@
+// Package filter contains probabilistic set-membership filters, which can answer that a key is
+// definitely not in a set or that it may be, using much less memory than the set itself.
+//
+// Filters take a function that hashes keys to uint64. The hash should be of good quality, since
+// every bit of it is used. HashString, HashBytes, and HashInt are provided for common key types.
+// These are stable across processes and platforms, so that filters encoded with MarshalBinary can
+// be decoded elsewhere and still give the same answers.
+package filter
+
+import (
+	"golang.org/x/exp/constraints"
+
+	"github.com/bradenaw/juniper/internal/hash"
+)
+
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+// HashString returns a hash of s.
+func HashString(s string) uint64 {
+	h := uint64(fnvOffset)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= fnvPrime
+	}
+	return hash.Mix(h)
+}
+
+// HashBytes returns a hash of b. It is the same as HashString(string(b)).
+func HashBytes(b []byte) uint64 {
+	h := uint64(fnvOffset)
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= fnvPrime
+	}
+	return hash.Mix(h)
+}
+
+// HashInt returns a hash of x.
+func HashInt[T constraints.Integer](x T) uint64 {
+	return hash.Mix(uint64(x))
+}
@@ -0,0 +1,96 @@
+// Package hashmap contains a hash map and set that use a user-supplied hash function and equality,
+// so that types that aren't comparable, like slices, can be used as keys.
+//
+// Equality can also be looser than Go's ==, for example to treat strings that differ only in case
+// as the same key.
+package hashmap
+
+import (
+	"github.com/bradenaw/juniper/iterator"
+)
+
+// KVPair is a key and its associated value.
+type KVPair[K any, V any] struct {
+	Key   K
+	Value V
+}
+
+// Map is a hash map, similar to Go's built-in map but using the given hash and equality functions
+// for keys.
+//
+// Map uses open addressing, so entries are stored in a single slice and lookups don't allocate.
+type Map[K any, V any] struct {
+	// An extra indirect here so that hashmap.Map behaves like a reference type like the map builtin.
+	t *table[K, V]
+}
+
+// NewMap returns a Map that uses hash and eq for keys. If eq(a, b), then hash(a) must equal
+// hash(b). Neither's output may change for a key while it is in the map.
+//
+// The bits of the output of hash are mixed before use, so hash does not need to be well-
+// distributed, though collisions between unequal keys make the map slower.
+func NewMap[K any, V any](hash func(K) uint64, eq func(a, b K) bool) Map[K, V] {
+	return Map[K, V]{
+		t: newTable[K, V](hash, eq),
+	}
+}
+
+// Len returns the number of elements in the map.
+func (m Map[K, V]) Len() int {
+	return m.t.size
+}
+
+// Put inserts the key-value pair into the map, overwriting the value for the key if it already
+// exists. If the key already exists, the key already in the map is kept.
+func (m Map[K, V]) Put(k K, v V) {
+	m.t.Put(k, v)
+}
+
+// Delete removes the given key from the map.
+func (m Map[K, V]) Delete(k K) {
+	m.t.Delete(k)
+}
+
+// Get returns the value associated with the given key if it is present in the map. Otherwise, it
+// returns the zero-value of V.
+func (m Map[K, V]) Get(k K) V {
+	v, _ := m.t.Get(k)
+	return v
+}
+
+// Lookup returns the value associated with the given key, and false in the second return if the
+// key is not present in the map.
+func (m Map[K, V]) Lookup(k K) (V, bool) {
+	return m.t.Get(k)
+}
+
+// Contains returns true if the given key is present in the map.
+func (m Map[K, V]) Contains(k K) bool {
+	_, ok := m.t.Get(k)
+	return ok
+}
+
+// Clear removes all elements from the map.
+func (m Map[K, V]) Clear() {
+	m.t.Clear()
+}
+
+// Iterate returns an iterator that yields the elements of the map in an arbitrary order.
+//
+// The iterator panics if the map has elements added or removed during iteration. Changing the
+// value for a key that is already in the map with Put is allowed.
+func (m Map[K, V]) Iterate() iterator.Iterator[KVPair[K, V]] {
+	return &mapIterator[K, V]{inner: tableIterator[K, V]{t: m.t, gen: -1}}
+}
+
+type mapIterator[K any, V any] struct {
+	inner tableIterator[K, V]
+}
+
+func (iter *mapIterator[K, V]) Next() (KVPair[K, V], bool) {
+	s, ok := iter.inner.next()
+	if !ok {
+		return KVPair[K, V]{}, false
+	}
+	return KVPair[K, V]{Key: s.key, Value: s.value}, true
+}
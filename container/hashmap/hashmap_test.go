@@ -0,0 +1,163 @@
+package hashmap
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"testing"
+
+	"github.com/bradenaw/juniper/internal/fuzz"
+	"github.com/bradenaw/juniper/internal/require2"
+	"github.com/bradenaw/juniper/iterator"
+)
+
+func hashBytes(b []byte) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write(b)
+	return h.Sum64()
+}
+
+func FuzzMap(f *testing.F) {
+	// Make keys out of a byte so that the fuzzer can find collisions, and check both a reasonable
+	// hash and a terrible one.
+	key := func(b byte) []byte { return []byte{b % 3, b} }
+	hashes := map[string]func([]byte) uint64{
+		"fnv":      hashBytes,
+		"terrible": func(b []byte) uint64 { return uint64(b[1] % 4) },
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		for name, hash := range hashes {
+			t.Run(name, func(t *testing.T) {
+				m := NewMap[[]byte, int](hash, bytes.Equal)
+				oracle := make(map[byte]int)
+
+				fuzz.Operations(
+					b,
+					func() { // check
+						require2.Equal(t, len(oracle), m.Len())
+						var expected []string
+						for k, v := range oracle {
+							expected = append(expected, fmt.Sprintf("%v=%d", key(k), v))
+						}
+						var actual []string
+						iter := m.Iterate()
+						for {
+							kv, ok := iter.Next()
+							if !ok {
+								break
+							}
+							actual = append(actual, fmt.Sprintf("%v=%d", kv.Key, kv.Value))
+						}
+						require2.ElementsMatch(t, expected, actual)
+					},
+					func(k byte, v int) {
+						t.Logf("Put(%v, %d)", key(k), v)
+						m.Put(key(k), v)
+						oracle[k] = v
+					},
+					func(k byte) {
+						t.Logf("Delete(%v)", key(k))
+						m.Delete(key(k))
+						delete(oracle, k)
+					},
+					func(k byte) {
+						expected, expectedOk := oracle[k]
+						v, ok := m.Lookup(key(k))
+						require2.Equal(t, expectedOk, ok)
+						require2.Equal(t, expected, v)
+						require2.Equal(t, expected, m.Get(key(k)))
+						require2.Equal(t, expectedOk, m.Contains(key(k)))
+					},
+					func() {
+						t.Logf("Clear()")
+						m.Clear()
+						oracle = make(map[byte]int)
+					},
+				)
+			})
+		}
+	})
+}
+
+func TestMapLarge(t *testing.T) {
+	m := NewMap[[]byte, int](hashBytes, bytes.Equal)
+	for i := 0; i < 10000; i++ {
+		m.Put([]byte(fmt.Sprint(i)), i)
+	}
+	require2.Equal(t, 10000, m.Len())
+	for i := 0; i < 10000; i += 2 {
+		m.Delete([]byte(fmt.Sprint(i)))
+	}
+	require2.Equal(t, 5000, m.Len())
+	for i := 0; i < 10000; i++ {
+		v, ok := m.Lookup([]byte(fmt.Sprint(i)))
+		require2.Equal(t, i%2 == 1, ok)
+		if ok {
+			require2.Equal(t, i, v)
+		}
+	}
+}
+
+func TestIterateModified(t *testing.T) {
+	s := NewSet[[]byte](hashBytes, bytes.Equal)
+	s.Add([]byte("a"))
+	s.Add([]byte("b"))
+	iter := s.Iterate()
+	_, ok := iter.Next()
+	require2.True(t, ok)
+	// Adding an item that's already present isn't a modification.
+	s.Add([]byte("a"))
+	_, ok = iter.Next()
+	require2.True(t, ok)
+
+	// Modifying before the first call to Next is allowed.
+	iter2 := s.Iterate()
+	s.Add([]byte("c"))
+	_, ok = iter2.Next()
+	require2.True(t, ok)
+
+	s.Remove([]byte("a"))
+	defer func() {
+		require2.True(t, recover() == errModified)
+	}()
+	iter.Next()
+}
+
+func ExampleSet() {
+	// Case-insensitive ASCII strings. Any strings that are equal must hash the same, so hash the
+	// lowercase form.
+	hash := func(s string) uint64 { return hashBytes([]byte(strings.ToLower(s))) }
+	eq := func(a, b string) bool { return strings.ToLower(a) == strings.ToLower(b) }
+	s := NewSet[string](hash, eq)
+
+	s.Add("Hello")
+	s.Add("HELLO")
+	s.Add("world")
+
+	fmt.Println(s.Len())
+	fmt.Println(s.Contains("hello"))
+	fmt.Println(s.Contains("World"))
+	fmt.Println(s.Contains("goodbye"))
+
+	// Output:
+	// 2
+	// true
+	// true
+	// false
+}
+
+func ExampleMap() {
+	// Slices can't be used as keys in a Go map.
+	m := NewMap[[]string, int](
+		func(path []string) uint64 { return hashBytes([]byte(strings.Join(path, "\x00"))) },
+		func(a, b []string) bool { return iterator.Equal(iterator.Slice(a), iterator.Slice(b)) },
+	)
+	m.Put([]string{"usr", "bin"}, 1)
+	m.Put([]string{"usr", "lib"}, 2)
+
+	fmt.Println(m.Get([]string{"usr", "lib"}))
+
+	// Output:
+	// 2
+}
@@ -0,0 +1,66 @@
+package hashmap
+
+import (
+	"github.com/bradenaw/juniper/iterator"
+)
+
+// Set is a hash set, similar to Go's built-in map[T]struct{} but using the given hash and equality
+// functions for items.
+type Set[T any] struct {
+	// An extra indirect here so that hashmap.Set behaves like a reference type like the map builtin.
+	t *table[T, struct{}]
+}
+
+// NewSet returns a Set that uses hash and eq for items. If eq(a, b), then hash(a) must equal
+// hash(b). Neither's output may change for an item while it is in the set.
+func NewSet[T any](hash func(T) uint64, eq func(a, b T) bool) Set[T] {
+	return Set[T]{
+		t: newTable[T, struct{}](hash, eq),
+	}
+}
+
+// Len returns the number of elements in the set.
+func (s Set[T]) Len() int {
+	return s.t.size
+}
+
+// Add adds item to the set if it is not already present.
+func (s Set[T]) Add(item T) {
+	s.t.Put(item, struct{}{})
+}
+
+// Remove removes item from the set if it is present, and does nothing otherwise.
+func (s Set[T]) Remove(item T) {
+	s.t.Delete(item)
+}
+
+// Contains returns true if item is present in the set.
+func (s Set[T]) Contains(item T) bool {
+	_, ok := s.t.Get(item)
+	return ok
+}
+
+// Clear removes all elements from the set.
+func (s Set[T]) Clear() {
+	s.t.Clear()
+}
+
+// Iterate returns an iterator that yields the elements of the set in an arbitrary order.
+//
+// The iterator panics if the set is modified during iteration.
+func (s Set[T]) Iterate() iterator.Iterator[T] {
+	return &setIterator[T]{inner: tableIterator[T, struct{}]{t: s.t, gen: -1}}
+}
+
+type setIterator[T any] struct {
+	inner tableIterator[T, struct{}]
+}
+
+func (iter *setIterator[T]) Next() (T, bool) {
+	s, ok := iter.inner.next()
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return s.key, true
+}
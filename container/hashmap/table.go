@@ -0,0 +1,148 @@
+package hashmap
+
+import (
+	"errors"
+
+	"github.com/bradenaw/juniper/internal/hash"
+)
+
+var errModified = errors.New("map modified during iteration")
+
+const minCapacity = 8
+
+// table is an open-addressing hash table with linear probing.
+type table[K any, V any] struct {
+	hash func(K) uint64
+	eq   func(a, b K) bool
+	// len(slots) is always a power of two, and at most 3/4 of them are used.
+	slots []slot[K, V]
+	size  int
+	// Incremented whenever the table is modified, to detect modification during iteration.
+	gen int
+}
+
+type slot[K any, V any] struct {
+	used bool
+	// The mixed hash of key, stored to avoid calling eq for keys that can't match and to avoid
+	// rehashing when growing.
+	hash  uint64
+	key   K
+	value V
+}
+
+func newTable[K any, V any](hash func(K) uint64, eq func(a, b K) bool) *table[K, V] {
+	return &table[K, V]{
+		hash:  hash,
+		eq:    eq,
+		slots: make([]slot[K, V], minCapacity),
+	}
+}
+
+func (t *table[K, V]) mask() uint64 {
+	return uint64(len(t.slots) - 1)
+}
+
+// find returns the index of the slot holding k, or of the empty slot where k would go and false.
+func (t *table[K, V]) find(k K, h uint64) (uint64, bool) {
+	mask := t.mask()
+	for i := h & mask; ; i = (i + 1) & mask {
+		s := &t.slots[i]
+		if !s.used {
+			return i, false
+		}
+		if s.hash == h && t.eq(s.key, k) {
+			return i, true
+		}
+	}
+}
+
+func (t *table[K, V]) Get(k K) (V, bool) {
+	i, ok := t.find(k, hash.Mix(t.hash(k)))
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return t.slots[i].value, true
+}
+
+func (t *table[K, V]) Put(k K, v V) {
+	h := hash.Mix(t.hash(k))
+	i, ok := t.find(k, h)
+	if ok {
+		t.slots[i].value = v
+		return
+	}
+	t.gen++
+	if (t.size+1)*4 > len(t.slots)*3 {
+		t.resize(len(t.slots) * 2)
+		i, _ = t.find(k, h)
+	}
+	t.slots[i] = slot[K, V]{used: true, hash: h, key: k, value: v}
+	t.size++
+}
+
+func (t *table[K, V]) Delete(k K) {
+	i, ok := t.find(k, hash.Mix(t.hash(k)))
+	if !ok {
+		return
+	}
+	t.gen++
+	t.size--
+	// Rather than leaving a tombstone, shift back any following entries that would no longer be
+	// found after the hole, so that lookups can always stop at the first empty slot.
+	mask := t.mask()
+	hole := i
+	for j := (i + 1) & mask; t.slots[j].used; j = (j + 1) & mask {
+		ideal := t.slots[j].hash & mask
+		// The entry at j can move back to hole only if hole isn't before its ideal slot.
+		if (j-ideal)&mask >= (j-hole)&mask {
+			t.slots[hole] = t.slots[j]
+			hole = j
+		}
+	}
+	t.slots[hole] = slot[K, V]{}
+}
+
+func (t *table[K, V]) Clear() {
+	t.gen++
+	t.slots = make([]slot[K, V], minCapacity)
+	t.size = 0
+}
+
+func (t *table[K, V]) resize(n int) {
+	old := t.slots
+	t.slots = make([]slot[K, V], n)
+	mask := t.mask()
+	for _, s := range old {
+		if !s.used {
+			continue
+		}
+		i := s.hash & mask
+		for t.slots[i].used {
+			i = (i + 1) & mask
+		}
+		t.slots[i] = s
+	}
+}
+
+type tableIterator[K any, V any] struct {
+	t   *table[K, V]
+	gen int
+	i   int
+}
+
+func (iter *tableIterator[K, V]) next() (*slot[K, V], bool) {
+	if iter.gen == -1 {
+		iter.gen = iter.t.gen
+	} else if iter.gen != iter.t.gen {
+		panic(errModified)
+	}
+	for iter.i < len(iter.t.slots) {
+		s := &iter.t.slots[iter.i]
+		iter.i++
+		if s.used {
+			return s, true
+		}
+	}
+	return nil, false
+}
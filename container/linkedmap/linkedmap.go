@@ -0,0 +1,279 @@
+// Package linkedmap contains a hash map that remembers the order of its keys.
+package linkedmap
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/bradenaw/juniper/container/xlist"
+	"github.com/bradenaw/juniper/iterator"
+)
+
+// KVPair is a key and the value associated with it, as yielded by Map's iterators.
+type KVPair[K any, V any] struct {
+	Key   K
+	Value V
+}
+
+// Map is a hash map that keeps its entries in order. By default the order is the order in which
+// keys were first added. A Map created with NewAccessOrdered instead moves entries to the back
+// whenever they are accessed, so the front is the least-recently used.
+//
+// Get, Put, Delete, MoveToFront, and MoveToBack take O(1) time.
+//
+// The zero value is an empty insertion-ordered Map ready to use. Map is not safe for concurrent
+// use.
+type Map[K comparable, V any] struct {
+	accessOrder bool
+	l           xlist.List[KVPair[K, V]]
+	m           map[K]*xlist.Node[KVPair[K, V]]
+}
+
+// New returns an empty Map that iterates in the order keys were first added. Overwriting the value
+// of a key with Put does not change its position.
+func New[K comparable, V any]() *Map[K, V] {
+	return &Map[K, V]{}
+}
+
+// NewAccessOrdered returns an empty Map that iterates in the order keys were last accessed, from
+// least- to most-recently. Get and Put count as accesses.
+func NewAccessOrdered[K comparable, V any]() *Map[K, V] {
+	return &Map[K, V]{accessOrder: true}
+}
+
+// Len returns the number of entries in the map.
+func (m *Map[K, V]) Len() int {
+	return len(m.m)
+}
+
+// Put sets the value for k to v. If k is not already present it is added at the back.
+func (m *Map[K, V]) Put(k K, v V) {
+	node, ok := m.m[k]
+	if ok {
+		node.Value.Value = v
+		if m.accessOrder {
+			m.l.MoveToBack(node)
+		}
+		return
+	}
+	if m.m == nil {
+		m.m = make(map[K]*xlist.Node[KVPair[K, V]])
+	}
+	m.m[k] = m.l.PushBack(KVPair[K, V]{Key: k, Value: v})
+}
+
+// Get returns the value associated with the given key if it is present in the map. Otherwise, it
+// returns the zero-value of V.
+func (m *Map[K, V]) Get(k K) V {
+	node, ok := m.m[k]
+	if !ok {
+		var zero V
+		return zero
+	}
+	if m.accessOrder {
+		m.l.MoveToBack(node)
+	}
+	return node.Value.Value
+}
+
+// Contains returns true if the given key is present in the map. It does not count as an access.
+func (m *Map[K, V]) Contains(k K) bool {
+	_, ok := m.m[k]
+	return ok
+}
+
+// Delete removes the given key from the map.
+func (m *Map[K, V]) Delete(k K) {
+	node, ok := m.m[k]
+	if !ok {
+		return
+	}
+	m.l.Remove(node)
+	delete(m.m, k)
+}
+
+// Clear removes all entries from the map.
+func (m *Map[K, V]) Clear() {
+	m.l.Clear()
+	m.m = nil
+}
+
+// MoveToFront moves k to the front of the map. Returns false if k is not present.
+func (m *Map[K, V]) MoveToFront(k K) bool {
+	node, ok := m.m[k]
+	if !ok {
+		return false
+	}
+	m.l.MoveToFront(node)
+	return true
+}
+
+// MoveToBack moves k to the back of the map. Returns false if k is not present.
+func (m *Map[K, V]) MoveToBack(k K) bool {
+	node, ok := m.m[k]
+	if !ok {
+		return false
+	}
+	m.l.MoveToBack(node)
+	return true
+}
+
+// First returns the entry at the front of the map, or zero values if the map is empty.
+func (m *Map[K, V]) First() (K, V) {
+	node := m.l.Front()
+	if node == nil {
+		var zeroK K
+		var zeroV V
+		return zeroK, zeroV
+	}
+	return node.Value.Key, node.Value.Value
+}
+
+// Last returns the entry at the back of the map, or zero values if the map is empty.
+func (m *Map[K, V]) Last() (K, V) {
+	node := m.l.Back()
+	if node == nil {
+		var zeroK K
+		var zeroV V
+		return zeroK, zeroV
+	}
+	return node.Value.Key, node.Value.Value
+}
+
+// Iterate returns an iterator that yields the entries of the map from front to back.
+//
+// It is safe to Delete the most-recently yielded key during iteration. The iterator is otherwise
+// invalidated if the map is modified.
+func (m *Map[K, V]) Iterate() iterator.Iterator[KVPair[K, V]] {
+	return m.l.Iterate()
+}
+
+// Backward returns an iterator that yields the entries of the map from back to front.
+//
+// It is safe to Delete the most-recently yielded key during iteration. The iterator is otherwise
+// invalidated if the map is modified.
+func (m *Map[K, V]) Backward() iterator.Iterator[KVPair[K, V]] {
+	return m.l.Backward()
+}
+
+// MarshalJSON encodes the map as a JSON object with its keys in order.
+//
+// Keys are encoded the same way that encoding/json encodes the keys of a map builtin, so K must be
+// a string or integer type or implement encoding.TextMarshaler.
+//
+// MarshalJSON has a value receiver so that a Map is encoded correctly even when it is not
+// addressable, such as a field of a struct passed to json.Marshal by value.
+func (m Map[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for node := m.l.Front(); node != nil; node = node.Next() {
+		if node != m.l.Front() {
+			buf.WriteByte(',')
+		}
+		ks, err := encodeKey(node.Value.Key)
+		if err != nil {
+			return nil, err
+		}
+		kb, err := json.Marshal(ks)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(kb)
+		buf.WriteByte(':')
+		vb, err := json.Marshal(node.Value.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(vb)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a JSON object into the map, adding its entries in the order they appear.
+// Like decoding into a map builtin, existing entries are kept unless overwritten.
+func (m *Map[K, V]) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		// null leaves the map unchanged.
+		return nil
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("cannot unmarshal %v into a Map", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var k K
+		err = decodeKey(tok.(string), &k)
+		if err != nil {
+			return err
+		}
+		var v V
+		err = dec.Decode(&v)
+		if err != nil {
+			return err
+		}
+		m.Put(k, v)
+	}
+	_, err = dec.Token()
+	return err
+}
+
+func encodeKey(k any) (string, error) {
+	rv := reflect.ValueOf(k)
+	if rv.Kind() == reflect.String {
+		return rv.String(), nil
+	}
+	if tm, ok := k.(encoding.TextMarshaler); ok {
+		b, err := tm.MarshalText()
+		return string(b), err
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported key type %T", k)
+}
+
+func decodeKey(s string, k any) error {
+	if tu, ok := k.(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(s))
+	}
+	rv := reflect.ValueOf(k).Elem()
+	if rv.Kind() == reflect.String {
+		rv.SetString(s)
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, rv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid key %q for %s: %w", s, rv.Type(), err)
+		}
+		rv.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, rv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid key %q for %s: %w", s, rv.Type(), err)
+		}
+		rv.SetUint(n)
+		return nil
+	}
+	return fmt.Errorf("unsupported key type %s", rv.Type())
+}
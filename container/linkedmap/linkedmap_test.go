@@ -0,0 +1,203 @@
+package linkedmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bradenaw/juniper/internal/fuzz"
+	"github.com/bradenaw/juniper/internal/require2"
+	"github.com/bradenaw/juniper/iterator"
+	"github.com/bradenaw/juniper/xslices"
+)
+
+func FuzzMap(f *testing.F) {
+	f.Fuzz(func(t *testing.T, accessOrder bool, b []byte) {
+		var m *Map[byte, int]
+		if accessOrder {
+			t.Logf("NewAccessOrdered()")
+			m = NewAccessOrdered[byte, int]()
+		} else {
+			t.Logf("New()")
+			m = New[byte, int]()
+		}
+		var oracle []KVPair[byte, int]
+		indexOf := func(k byte) int {
+			return xslices.IndexFunc(oracle, func(kv KVPair[byte, int]) bool { return kv.Key == k })
+		}
+		moveToBack := func(i int) {
+			kv := oracle[i]
+			oracle = xslices.Remove(oracle, i, 1)
+			oracle = append(oracle, kv)
+		}
+		ctr := 0
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				require2.Equal(t, len(oracle), m.Len())
+				require2.SlicesEqual(t, oracle, iterator.Collect(m.Iterate()))
+				reversed := xslices.Clone(oracle)
+				xslices.Reverse(reversed)
+				require2.SlicesEqual(t, reversed, iterator.Collect(m.Backward()))
+				k, v := m.First()
+				if len(oracle) > 0 {
+					require2.Equal(t, oracle[0], KVPair[byte, int]{k, v})
+					k, v = m.Last()
+					require2.Equal(t, oracle[len(oracle)-1], KVPair[byte, int]{k, v})
+				} else {
+					require2.Equal(t, KVPair[byte, int]{}, KVPair[byte, int]{k, v})
+				}
+			},
+			func(k byte) {
+				k = k % 16
+				v := ctr
+				ctr++
+				t.Logf("Put(%d, %d)", k, v)
+				m.Put(k, v)
+				i := indexOf(k)
+				if i == -1 {
+					oracle = append(oracle, KVPair[byte, int]{k, v})
+					return
+				}
+				oracle[i].Value = v
+				if accessOrder {
+					moveToBack(i)
+				}
+			},
+			func(k byte) {
+				k = k % 16
+				t.Logf("Get(%d)", k)
+				v := m.Get(k)
+				i := indexOf(k)
+				require2.Equal(t, i != -1, m.Contains(k))
+				if i == -1 {
+					require2.Equal(t, 0, v)
+					return
+				}
+				require2.Equal(t, oracle[i].Value, v)
+				if accessOrder {
+					moveToBack(i)
+				}
+			},
+			func(k byte) {
+				k = k % 16
+				t.Logf("Delete(%d)", k)
+				m.Delete(k)
+				if i := indexOf(k); i != -1 {
+					oracle = xslices.Remove(oracle, i, 1)
+				}
+			},
+			func(k byte) {
+				k = k % 16
+				t.Logf("MoveToBack(%d)", k)
+				i := indexOf(k)
+				require2.Equal(t, i != -1, m.MoveToBack(k))
+				if i != -1 {
+					moveToBack(i)
+				}
+			},
+			func(k byte) {
+				k = k % 16
+				t.Logf("MoveToFront(%d)", k)
+				i := indexOf(k)
+				require2.Equal(t, i != -1, m.MoveToFront(k))
+				if i != -1 {
+					kv := oracle[i]
+					oracle = xslices.Remove(oracle, i, 1)
+					oracle = xslices.Insert(oracle, 0, kv)
+				}
+			},
+			func() {
+				t.Logf("Clear()")
+				m.Clear()
+				oracle = nil
+			},
+		)
+	})
+}
+
+func TestMapJSON(t *testing.T) {
+	m := New[string, int]()
+	m.Put("c", 1)
+	m.Put("a", 2)
+	m.Put("b", 3)
+	m.Put("a", 4)
+
+	b, err := json.Marshal(m)
+	require2.NoError(t, err)
+	require2.Equal(t, `{"c":1,"a":4,"b":3}`, string(b))
+
+	var m2 Map[string, int]
+	require2.NoError(t, json.Unmarshal(b, &m2))
+	require2.SlicesEqual(t, iterator.Collect(m.Iterate()), iterator.Collect(m2.Iterate()))
+
+	var s struct {
+		M Map[int16, string] `json:"m"`
+	}
+	require2.NoError(t, json.Unmarshal([]byte(`{"m": {"5": "x", "-2": "", "3": "z"}}`), &s))
+	require2.SlicesEqual(
+		t,
+		[]KVPair[int16, string]{{5, "x"}, {-2, ""}, {3, "z"}},
+		iterator.Collect(s.M.Iterate()),
+	)
+	b, err = json.Marshal(&s)
+	require2.NoError(t, err)
+	require2.Equal(t, `{"m":{"5":"x","-2":"","3":"z"}}`, string(b))
+	// Maps that aren't addressable are encoded the same way.
+	b, err = json.Marshal(s)
+	require2.NoError(t, err)
+	require2.Equal(t, `{"m":{"5":"x","-2":"","3":"z"}}`, string(b))
+	b, err = json.Marshal(map[string]Map[int16, string]{"x": s.M})
+	require2.NoError(t, err)
+	require2.Equal(t, `{"x":{"5":"x","-2":"","3":"z"}}`, string(b))
+
+	require2.Error(t, json.Unmarshal([]byte(`{"m": {"100000": ""}}`), &s))
+	require2.Error(t, json.Unmarshal([]byte(`{"m": []}`), &s))
+}
+
+// lowerKey is a string that is lowercased when decoded from text.
+type lowerKey string
+
+func (k *lowerKey) UnmarshalText(b []byte) error {
+	*k = lowerKey(strings.ToLower(string(b)))
+	return nil
+}
+
+func TestMapJSONTextUnmarshalerKey(t *testing.T) {
+	// Like encoding/json, UnmarshalText is preferred even though lowerKey is a string kind.
+	var m Map[lowerKey, int]
+	require2.NoError(t, json.Unmarshal([]byte(`{"B": 1, "a": 2}`), &m))
+	require2.SlicesEqual(
+		t,
+		[]KVPair[lowerKey, int]{{"b", 1}, {"a", 2}},
+		iterator.Collect(m.Iterate()),
+	)
+}
+
+func ExampleMap() {
+	m := New[string, int]()
+	m.Put("zebra", 3)
+	m.Put("apple", 1)
+	m.Put("mango", 2)
+
+	b, _ := json.Marshal(m)
+	fmt.Println(string(b))
+
+	m.MoveToBack("zebra")
+	iter := m.Iterate()
+	for {
+		kv, ok := iter.Next()
+		if !ok {
+			break
+		}
+		fmt.Println(kv.Key, kv.Value)
+	}
+
+	// Output:
+	// {"zebra":3,"apple":1,"mango":2}
+	// apple 1
+	// mango 2
+	// zebra 3
+}
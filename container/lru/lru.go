@@ -0,0 +1,253 @@
+// Package lru contains a least-recently-used cache.
+package lru
+
+import (
+	"time"
+
+	"github.com/bradenaw/juniper/container/xlist"
+	"github.com/bradenaw/juniper/iterator"
+)
+
+// KVPair is a key and the value associated with it, as yielded by Cache.Iterate.
+type KVPair[K any, V any] struct {
+	Key   K
+	Value V
+}
+
+// EvictReason describes why an entry was evicted from a Cache.
+type EvictReason int
+
+const (
+	// EvictedCapacity means the entry was evicted to make room for other entries.
+	EvictedCapacity EvictReason = iota + 1
+	// EvictedExpired means the entry was evicted because its TTL passed.
+	EvictedExpired
+)
+
+// Options configures a Cache. The zero value is a valid configuration.
+type Options[K any, V any] struct {
+	// Cost returns the cost of an entry, which counts against the capacity of the cache. If nil,
+	// every entry costs 1 so that capacity is the maximum number of entries.
+	Cost func(K, V) int
+	// TTL is the default time-to-live for entries added with Put. Zero means entries do not
+	// expire.
+	TTL time.Duration
+	// OnEvict, if non-nil, is called whenever the cache evicts an entry because of capacity or
+	// expiry. It is not called for entries removed with Remove or overwritten with Put.
+	OnEvict func(k K, v V, reason EvictReason)
+	// Now returns the current time, used for TTLs. If nil, uses time.Now.
+	Now func() time.Time
+}
+
+// Stats holds counters describing the usage of a Cache.
+type Stats struct {
+	// The number of calls to Get that found an entry.
+	Hits uint64
+	// The number of calls to Get that did not find an entry.
+	Misses uint64
+	// The number of entries evicted because of capacity or expiry.
+	Evictions uint64
+}
+
+// Cache is a key-value cache with a fixed capacity. When adding an entry would put the cache over
+// capacity, the least-recently used entries are evicted to make room. Entries can also optionally
+// expire after a time-to-live.
+//
+// Get, Peek, Put, and Remove take O(1) time, plus the time to evict any entries as a result.
+//
+// Cache is not safe for concurrent use. Note that Get modifies the cache, since it marks the entry
+// as recently used.
+type Cache[K comparable, V any] struct {
+	opts     Options[K, V]
+	capacity int
+	cost     int
+	// Front is most-recently used.
+	l     xlist.List[entry[K, V]]
+	m     map[K]*xlist.Node[entry[K, V]]
+	stats Stats
+}
+
+type entry[K any, V any] struct {
+	k    K
+	v    V
+	cost int
+	// Zero if the entry does not expire.
+	expires time.Time
+}
+
+// New returns an empty Cache with the given capacity.
+func New[K comparable, V any](capacity int, opts Options[K, V]) *Cache[K, V] {
+	if opts.Cost == nil {
+		opts.Cost = func(K, V) int { return 1 }
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Cache[K, V]{
+		opts:     opts,
+		capacity: capacity,
+		m:        make(map[K]*xlist.Node[entry[K, V]]),
+	}
+}
+
+// Len returns the number of entries in the cache. This may include entries that have expired but
+// have not yet been evicted.
+func (c *Cache[K, V]) Len() int {
+	return len(c.m)
+}
+
+// Cost returns the sum of the costs of all of the entries in the cache.
+func (c *Cache[K, V]) Cost() int {
+	return c.cost
+}
+
+// Capacity returns the capacity of the cache.
+func (c *Cache[K, V]) Capacity() int {
+	return c.capacity
+}
+
+// Stats returns counters describing the usage of the cache so far.
+func (c *Cache[K, V]) Stats() Stats {
+	return c.stats
+}
+
+// Get returns the value for k and marks it as most-recently used, if it is present and not
+// expired. Otherwise, it returns false in the second return.
+func (c *Cache[K, V]) Get(k K) (V, bool) {
+	node, ok := c.lookup(k)
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	c.l.MoveToFront(node)
+	return node.Value.v, true
+}
+
+// Peek returns the value for k if it is present and not expired, without marking it as used or
+// affecting Stats.
+func (c *Cache[K, V]) Peek(k K) (V, bool) {
+	node, ok := c.m[k]
+	if !ok || c.expired(&node.Value) {
+		var zero V
+		return zero, false
+	}
+	return node.Value.v, true
+}
+
+// Contains returns true if k is present and not expired, without marking it as used or affecting
+// Stats.
+func (c *Cache[K, V]) Contains(k K) bool {
+	_, ok := c.Peek(k)
+	return ok
+}
+
+// Put adds an entry for k with value v, replacing any existing entry, and marks it as
+// most-recently used. The entry expires after the TTL from Options, if any.
+//
+// If this causes the cache to exceed its capacity, least-recently used entries are evicted. Note
+// that if the cost of this entry alone exceeds the capacity, it is evicted as well.
+func (c *Cache[K, V]) Put(k K, v V) {
+	c.PutTTL(k, v, c.opts.TTL)
+}
+
+// PutTTL is like Put, except the entry expires after ttl instead of the TTL from Options. A zero
+// ttl means the entry does not expire.
+func (c *Cache[K, V]) PutTTL(k K, v V, ttl time.Duration) {
+	e := entry[K, V]{
+		k:    k,
+		v:    v,
+		cost: c.opts.Cost(k, v),
+	}
+	if ttl != 0 {
+		e.expires = c.opts.Now().Add(ttl)
+	}
+	node, ok := c.m[k]
+	if ok {
+		c.cost -= node.Value.cost
+		node.Value = e
+		c.l.MoveToFront(node)
+	} else {
+		c.m[k] = c.l.PushFront(e)
+	}
+	c.cost += e.cost
+	c.evictToCapacity()
+}
+
+// Remove removes the entry for k, if present. Returns true if an entry was removed.
+func (c *Cache[K, V]) Remove(k K) bool {
+	node, ok := c.m[k]
+	if !ok {
+		return false
+	}
+	c.removeNode(node)
+	return true
+}
+
+// Resize changes the capacity of the cache, evicting least-recently used entries if necessary.
+func (c *Cache[K, V]) Resize(capacity int) {
+	c.capacity = capacity
+	c.evictToCapacity()
+}
+
+// Clear removes all entries from the cache without calling OnEvict.
+func (c *Cache[K, V]) Clear() {
+	c.l.Clear()
+	c.m = make(map[K]*xlist.Node[entry[K, V]])
+	c.cost = 0
+}
+
+// Iterate iterates over the unexpired entries of the cache from most- to least-recently used,
+// without marking any of them as used.
+//
+// It is safe to Remove the most recently yielded key during iteration. The iterator is otherwise
+// invalidated if the cache is modified.
+func (c *Cache[K, V]) Iterate() iterator.Iterator[KVPair[K, V]] {
+	return iterator.Map(
+		iterator.Filter(c.l.Iterate(), func(e entry[K, V]) bool { return !c.expired(&e) }),
+		func(e entry[K, V]) KVPair[K, V] { return KVPair[K, V]{e.k, e.v} },
+	)
+}
+
+// lookup returns the node for k if it is present and unexpired, evicting it if it is expired.
+func (c *Cache[K, V]) lookup(k K) (*xlist.Node[entry[K, V]], bool) {
+	node, ok := c.m[k]
+	if !ok {
+		return nil, false
+	}
+	if c.expired(&node.Value) {
+		c.evict(node, EvictedExpired)
+		return nil, false
+	}
+	return node, true
+}
+
+func (c *Cache[K, V]) expired(e *entry[K, V]) bool {
+	return !e.expires.IsZero() && !c.opts.Now().Before(e.expires)
+}
+
+func (c *Cache[K, V]) evictToCapacity() {
+	for c.cost > c.capacity && c.l.Len() > 0 {
+		node := c.l.Back()
+		reason := EvictedCapacity
+		if c.expired(&node.Value) {
+			reason = EvictedExpired
+		}
+		c.evict(node, reason)
+	}
+}
+
+func (c *Cache[K, V]) evict(node *xlist.Node[entry[K, V]], reason EvictReason) {
+	c.removeNode(node)
+	c.stats.Evictions++
+	if c.opts.OnEvict != nil {
+		c.opts.OnEvict(node.Value.k, node.Value.v, reason)
+	}
+}
+
+func (c *Cache[K, V]) removeNode(node *xlist.Node[entry[K, V]]) {
+	c.l.Remove(node)
+	delete(c.m, node.Value.k)
+	c.cost -= node.Value.cost
+}
@@ -0,0 +1,181 @@
+package lru
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bradenaw/juniper/internal/fuzz"
+	"github.com/bradenaw/juniper/internal/require2"
+	"github.com/bradenaw/juniper/iterator"
+	"github.com/bradenaw/juniper/xslices"
+)
+
+func FuzzCache(f *testing.F) {
+	f.Fuzz(func(t *testing.T, capacity byte, b []byte) {
+		capacity = capacity % 8
+		t.Logf("New(%d)", capacity)
+		var evicted []KVPair[byte, int]
+		c := New(int(capacity), Options[byte, int]{
+			OnEvict: func(k byte, v int, reason EvictReason) {
+				require2.Equal(t, EvictedCapacity, reason)
+				evicted = append(evicted, KVPair[byte, int]{k, v})
+			},
+		})
+		// Most-recently used first.
+		var oracle []KVPair[byte, int]
+		var oracleEvicted []KVPair[byte, int]
+		var oracleStats Stats
+		indexOf := func(k byte) int {
+			return xslices.IndexFunc(oracle, func(kv KVPair[byte, int]) bool { return kv.Key == k })
+		}
+		ctr := 0
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				require2.Equal(t, len(oracle), c.Len())
+				require2.Equal(t, len(oracle), c.Cost())
+				require2.SlicesEqual(t, oracle, iterator.Collect(c.Iterate()))
+				require2.SlicesEqual(t, oracleEvicted, evicted)
+				require2.Equal(t, oracleStats, c.Stats())
+			},
+			func(k byte) {
+				k = k % 16
+				v := ctr
+				ctr++
+				t.Logf("Put(%d, %d)", k, v)
+				c.Put(k, v)
+				if i := indexOf(k); i != -1 {
+					oracle = xslices.Remove(oracle, i, 1)
+				}
+				oracle = xslices.Insert(oracle, 0, KVPair[byte, int]{k, v})
+				for len(oracle) > int(capacity) {
+					oracleEvicted = append(oracleEvicted, oracle[len(oracle)-1])
+					oracleStats.Evictions++
+					oracle = oracle[:len(oracle)-1]
+				}
+			},
+			func(k byte) {
+				k = k % 16
+				t.Logf("Get(%d)", k)
+				v, ok := c.Get(k)
+				i := indexOf(k)
+				require2.Equal(t, i != -1, ok)
+				if i == -1 {
+					oracleStats.Misses++
+					return
+				}
+				oracleStats.Hits++
+				require2.Equal(t, oracle[i].Value, v)
+				kv := oracle[i]
+				oracle = xslices.Remove(oracle, i, 1)
+				oracle = xslices.Insert(oracle, 0, kv)
+			},
+			func(k byte) {
+				k = k % 16
+				t.Logf("Peek(%d)", k)
+				v, ok := c.Peek(k)
+				i := indexOf(k)
+				require2.Equal(t, i != -1, ok)
+				if i != -1 {
+					require2.Equal(t, oracle[i].Value, v)
+				}
+			},
+			func(k byte) {
+				k = k % 16
+				t.Logf("Remove(%d)", k)
+				i := indexOf(k)
+				require2.Equal(t, i != -1, c.Remove(k))
+				if i != -1 {
+					oracle = xslices.Remove(oracle, i, 1)
+				}
+			},
+			func(newCapacity byte) {
+				capacity = newCapacity % 8
+				t.Logf("Resize(%d)", capacity)
+				c.Resize(int(capacity))
+				for len(oracle) > int(capacity) {
+					oracleEvicted = append(oracleEvicted, oracle[len(oracle)-1])
+					oracleStats.Evictions++
+					oracle = oracle[:len(oracle)-1]
+				}
+			},
+		)
+	})
+}
+
+func TestCacheTTL(t *testing.T) {
+	now := time.Unix(1000, 0)
+	var expired []string
+	c := New(10, Options[string, int]{
+		TTL: time.Minute,
+		Now: func() time.Time { return now },
+		OnEvict: func(k string, v int, reason EvictReason) {
+			require2.Equal(t, EvictedExpired, reason)
+			expired = append(expired, k)
+		},
+	})
+
+	c.Put("a", 1)
+	c.PutTTL("b", 2, 2*time.Minute)
+	c.PutTTL("c", 3, 0)
+
+	now = now.Add(time.Minute)
+	require2.True(t, !c.Contains("a"))
+	_, ok := c.Get("a")
+	require2.True(t, !ok)
+	require2.SlicesEqual(t, []string{"a"}, expired)
+
+	v, ok := c.Get("b")
+	require2.True(t, ok)
+	require2.Equal(t, 2, v)
+
+	now = now.Add(time.Hour)
+	require2.SlicesEqual(
+		t,
+		[]KVPair[string, int]{{"c", 3}},
+		iterator.Collect(c.Iterate()),
+	)
+	require2.Equal(t, Stats{Hits: 1, Misses: 1, Evictions: 1}, c.Stats())
+}
+
+func TestCacheCost(t *testing.T) {
+	c := New(10, Options[string, string]{
+		Cost: func(k string, v string) int { return len(v) },
+	})
+	c.Put("a", "xxxx")
+	c.Put("b", "xxxx")
+	require2.Equal(t, 8, c.Cost())
+	c.Get("a")
+	c.Put("c", "xxxx")
+	require2.Equal(t, 8, c.Cost())
+	require2.True(t, c.Contains("a"))
+	require2.True(t, !c.Contains("b"))
+	require2.True(t, c.Contains("c"))
+
+	c.Put("a", "x")
+	require2.Equal(t, 5, c.Cost())
+
+	// Too big to ever fit.
+	c.Put("d", "xxxxxxxxxxx")
+	require2.Equal(t, 0, c.Len())
+	require2.Equal(t, 0, c.Cost())
+}
+
+func ExampleCache() {
+	c := New(2, Options[string, int]{})
+
+	c.Put("a", 1)
+	c.Put("b", 2)
+	// Marks "a" as recently used, so "b" is evicted next.
+	c.Get("a")
+	c.Put("c", 3)
+
+	fmt.Println(iterator.Collect(c.Iterate()))
+	fmt.Println(c.Get("b"))
+
+	// Output:
+	// [{c 3} {a 1}]
+	// 0 false
+}
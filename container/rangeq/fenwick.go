@@ -0,0 +1,95 @@
+// Package rangeq contains structures for answering queries over ranges of an indexed sequence, like
+// the sum or maximum of a subslice, faster than scanning the range.
+package rangeq
+
+// Fenwick is a Fenwick tree, also known as a binary indexed tree. It holds a sequence of values and
+// can find the sum of any range of them, or change any of them, in O(log n) time.
+//
+// The values form an abelian group under add: add must be associative and commutative, the zero
+// value of T must be the identity, and subtract must undo add. Numeric types with + and - work, as
+// do for example vectors of numbers added element-wise. For operations without an inverse like max,
+// use SegmentTree instead.
+type Fenwick[T any] struct {
+	add      func(a, b T) T
+	subtract func(a, b T) T
+	// tree[i-1] holds the sum of the values in (i - lowbit(i), i], where lowbit(i) is the lowest set
+	// bit of i.
+	tree []T
+}
+
+// NewFenwick returns a Fenwick tree of n values, all of which are the zero value of T.
+func NewFenwick[T any](n int, add func(a, b T) T, subtract func(a, b T) T) *Fenwick[T] {
+	return &Fenwick[T]{
+		add:      add,
+		subtract: subtract,
+		tree:     make([]T, n),
+	}
+}
+
+// FenwickFromSlice returns a Fenwick tree holding the values in xs. It takes O(n) time.
+func FenwickFromSlice[T any](xs []T, add func(a, b T) T, subtract func(a, b T) T) *Fenwick[T] {
+	f := &Fenwick[T]{
+		add:      add,
+		subtract: subtract,
+		tree:     append([]T(nil), xs...),
+	}
+	for i := 1; i <= len(f.tree); i++ {
+		parent := i + (i & -i)
+		if parent <= len(f.tree) {
+			f.tree[parent-1] = f.add(f.tree[parent-1], f.tree[i-1])
+		}
+	}
+	return f
+}
+
+// Len returns the number of values in f.
+func (f *Fenwick[T]) Len() int {
+	return len(f.tree)
+}
+
+// Add adds delta to the i-th value.
+func (f *Fenwick[T]) Add(i int, delta T) {
+	checkIndex(i, len(f.tree))
+	for i++; i <= len(f.tree); i += i & -i {
+		f.tree[i-1] = f.add(f.tree[i-1], delta)
+	}
+}
+
+// Set sets the i-th value to x.
+func (f *Fenwick[T]) Set(i int, x T) {
+	f.Add(i, f.subtract(x, f.Get(i)))
+}
+
+// Get returns the i-th value.
+func (f *Fenwick[T]) Get(i int) T {
+	checkIndex(i, len(f.tree))
+	return f.Sum(i, i+1)
+}
+
+// Prefix returns the sum of the first i values, that is those in [0, i).
+func (f *Fenwick[T]) Prefix(i int) T {
+	checkRange(0, i, len(f.tree))
+	var sum T
+	for ; i > 0; i -= i & -i {
+		sum = f.add(sum, f.tree[i-1])
+	}
+	return sum
+}
+
+// Sum returns the sum of the values in [i, j).
+func (f *Fenwick[T]) Sum(i int, j int) T {
+	checkRange(i, j, len(f.tree))
+	return f.subtract(f.Prefix(j), f.Prefix(i))
+}
+
+func checkIndex(i int, n int) {
+	if i < 0 || i >= n {
+		panic("index out of range")
+	}
+}
+
+func checkRange(i int, j int, n int) {
+	if i < 0 || j > n || i > j {
+		panic("range out of bounds")
+	}
+}
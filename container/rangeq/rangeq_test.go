@@ -0,0 +1,232 @@
+package rangeq
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/bradenaw/juniper/internal/fuzz"
+	"github.com/bradenaw/juniper/internal/require2"
+)
+
+func add(a, b int) int      { return a + b }
+func subtract(a, b int) int { return a - b }
+
+func FuzzFenwick(f *testing.F) {
+	f.Fuzz(func(t *testing.T, n byte, b []byte) {
+		oracle := make([]int, int(n)%64)
+		for i := range oracle {
+			oracle[i] = i*7%13 - 6
+		}
+		fenwick := FenwickFromSlice(oracle, add, subtract)
+		oracle = append([]int(nil), oracle...)
+		if len(oracle) == 0 {
+			return
+		}
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				require2.Equal(t, len(oracle), fenwick.Len())
+				for i := range oracle {
+					require2.Equal(t, oracle[i], fenwick.Get(i))
+				}
+			},
+			func(i byte, delta int) {
+				idx := int(i) % len(oracle)
+				t.Logf("Add(%d, %d)", idx, delta)
+				fenwick.Add(idx, delta)
+				oracle[idx] += delta
+			},
+			func(i byte, x int) {
+				idx := int(i) % len(oracle)
+				t.Logf("Set(%d, %d)", idx, x)
+				fenwick.Set(idx, x)
+				oracle[idx] = x
+			},
+			func(i byte, j byte) {
+				lo, hi := int(i)%(len(oracle)+1), int(j)%(len(oracle)+1)
+				if lo > hi {
+					lo, hi = hi, lo
+				}
+				expected := 0
+				for _, x := range oracle[lo:hi] {
+					expected += x
+				}
+				require2.Equal(t, expected, fenwick.Sum(lo, hi))
+				if lo == 0 {
+					require2.Equal(t, expected, fenwick.Prefix(hi))
+				}
+			},
+		)
+	})
+}
+
+func FuzzSegmentTree(f *testing.F) {
+	f.Fuzz(func(t *testing.T, n byte, b []byte) {
+		// Concatenation isn't commutative, so this also checks that values are combined in order.
+		oracle := make([]string, int(n)%64)
+		for i := range oracle {
+			oracle[i] = fmt.Sprintf("%d,", i)
+		}
+		tree := NewSegmentTree(oracle, "", func(a, b string) string { return a + b })
+		oracle = append([]string(nil), oracle...)
+		if len(oracle) == 0 {
+			require2.Equal(t, "", tree.Query(0, 0))
+			return
+		}
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				require2.Equal(t, len(oracle), tree.Len())
+				for i := range oracle {
+					require2.Equal(t, oracle[i], tree.Get(i))
+				}
+			},
+			func(i byte, x byte) {
+				idx := int(i) % len(oracle)
+				t.Logf("Set(%d, %d)", idx, x)
+				tree.Set(idx, fmt.Sprintf("%d,", x))
+				oracle[idx] = fmt.Sprintf("%d,", x)
+			},
+			func(i byte, j byte) {
+				lo, hi := int(i)%(len(oracle)+1), int(j)%(len(oracle)+1)
+				if lo > hi {
+					lo, hi = hi, lo
+				}
+				expected := ""
+				for _, x := range oracle[lo:hi] {
+					expected += x
+				}
+				require2.Equal(t, expected, tree.Query(lo, hi))
+			},
+		)
+	})
+}
+
+func FuzzLazySegmentTree(f *testing.F) {
+	f.Fuzz(func(t *testing.T, n byte, b []byte) {
+		oracle := make([]int, int(n)%64)
+		for i := range oracle {
+			oracle[i] = i*7%13 - 6
+		}
+		// Range sums with range additions.
+		sums := NewLazySegmentTree(
+			oracle,
+			0,
+			add,
+			func(u int, x int, n int) int { return x + u*n },
+			add,
+		)
+		// Range maximums with range assignments.
+		maxes := NewLazySegmentTree(
+			oracle,
+			math.MinInt,
+			func(a, b int) int {
+				if a > b {
+					return a
+				}
+				return b
+			},
+			func(u int, x int, n int) int { return u },
+			func(newer, older int) int { return newer },
+		)
+		oracleSums := append([]int(nil), oracle...)
+		oracleMaxes := append([]int(nil), oracle...)
+		if len(oracle) == 0 {
+			return
+		}
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				for i := range oracle {
+					require2.Equal(t, oracleSums[i], sums.Get(i))
+					require2.Equal(t, oracleMaxes[i], maxes.Get(i))
+				}
+			},
+			func(i byte, j byte, delta int) {
+				lo, hi := int(i)%(len(oracle)+1), int(j)%(len(oracle)+1)
+				if lo > hi {
+					lo, hi = hi, lo
+				}
+				t.Logf("sums.Update(%d, %d, %d)", lo, hi, delta)
+				sums.Update(lo, hi, delta)
+				for k := lo; k < hi; k++ {
+					oracleSums[k] += delta
+				}
+			},
+			func(i byte, j byte, x int) {
+				lo, hi := int(i)%(len(oracle)+1), int(j)%(len(oracle)+1)
+				if lo > hi {
+					lo, hi = hi, lo
+				}
+				t.Logf("maxes.Update(%d, %d, %d)", lo, hi, x)
+				maxes.Update(lo, hi, x)
+				for k := lo; k < hi; k++ {
+					oracleMaxes[k] = x
+				}
+			},
+			func(i byte, x int) {
+				idx := int(i) % len(oracle)
+				t.Logf("Set(%d, %d)", idx, x)
+				sums.Set(idx, x)
+				maxes.Set(idx, x)
+				oracleSums[idx] = x
+				oracleMaxes[idx] = x
+			},
+			func(i byte, j byte) {
+				lo, hi := int(i)%(len(oracle)+1), int(j)%(len(oracle)+1)
+				if lo > hi {
+					lo, hi = hi, lo
+				}
+				expectedSum := 0
+				expectedMax := math.MinInt
+				for k := lo; k < hi; k++ {
+					expectedSum += oracleSums[k]
+					if oracleMaxes[k] > expectedMax {
+						expectedMax = oracleMaxes[k]
+					}
+				}
+				require2.Equal(t, expectedSum, sums.Query(lo, hi))
+				require2.Equal(t, expectedMax, maxes.Query(lo, hi))
+			},
+		)
+	})
+}
+
+func ExampleFenwick() {
+	// A histogram of latencies in 10ms buckets.
+	histogram := NewFenwick(10, add, subtract)
+	for _, latencyMs := range []int{3, 12, 15, 47, 8, 91, 33, 19} {
+		histogram.Add(latencyMs/10, 1)
+	}
+
+	fmt.Println("under 20ms:", histogram.Prefix(2))
+	fmt.Println("between 10ms and 50ms:", histogram.Sum(1, 5))
+
+	// Output:
+	// under 20ms: 5
+	// between 10ms and 50ms: 5
+}
+
+func ExampleSegmentTree() {
+	max := func(a, b int) int {
+		if a > b {
+			return a
+		}
+		return b
+	}
+	t := NewSegmentTree([]int{5, 1, 4, 1, 5, 9, 2, 6}, math.MinInt, max)
+
+	fmt.Println(t.Query(0, 4))
+	fmt.Println(t.Query(2, 8))
+	t.Set(5, 0)
+	fmt.Println(t.Query(2, 8))
+
+	// Output:
+	// 5
+	// 9
+	// 6
+}
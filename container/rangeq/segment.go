@@ -0,0 +1,244 @@
+package rangeq
+
+// SegmentTree holds a sequence of values and can combine any range of them, or change any of them,
+// in O(log n) time.
+//
+// combine must be associative, and identity must be its identity: combine(identity, x) and
+// combine(x, identity) must both be x. combine does not need to be commutative, and values are
+// always combined in the order they appear in the sequence. For example, combine can be + with
+// identity 0, max with identity math.MinInt, or string concatenation with identity "".
+type SegmentTree[T any] struct {
+	identity T
+	combine  func(a, b T) T
+	n        int
+	// tree[n+i] holds the i-th value, and tree[i] for 0 < i < n holds
+	// combine(tree[2*i], tree[2*i+1]).
+	tree []T
+}
+
+// NewSegmentTree returns a SegmentTree holding the values in xs. It takes O(n) time.
+func NewSegmentTree[T any](xs []T, identity T, combine func(a, b T) T) *SegmentTree[T] {
+	n := len(xs)
+	t := &SegmentTree[T]{
+		identity: identity,
+		combine:  combine,
+		n:        n,
+		tree:     make([]T, 2*n),
+	}
+	copy(t.tree[n:], xs)
+	for i := n - 1; i > 0; i-- {
+		t.tree[i] = combine(t.tree[2*i], t.tree[2*i+1])
+	}
+	return t
+}
+
+// Len returns the number of values in t.
+func (t *SegmentTree[T]) Len() int {
+	return t.n
+}
+
+// Get returns the i-th value.
+func (t *SegmentTree[T]) Get(i int) T {
+	checkIndex(i, t.n)
+	return t.tree[t.n+i]
+}
+
+// Set sets the i-th value to x.
+func (t *SegmentTree[T]) Set(i int, x T) {
+	checkIndex(i, t.n)
+	i += t.n
+	t.tree[i] = x
+	for i > 1 {
+		i /= 2
+		t.tree[i] = t.combine(t.tree[2*i], t.tree[2*i+1])
+	}
+}
+
+// Query returns the combination of the values in [i, j), or identity if i == j.
+func (t *SegmentTree[T]) Query(i int, j int) T {
+	checkRange(i, j, t.n)
+	// Values are combined from both ends towards the middle, keeping those from the left and right
+	// separate so that they stay in order.
+	left := t.identity
+	right := t.identity
+	for i, j = i+t.n, j+t.n; i < j; i, j = i/2, j/2 {
+		if i%2 == 1 {
+			left = t.combine(left, t.tree[i])
+			i++
+		}
+		if j%2 == 1 {
+			j--
+			right = t.combine(t.tree[j], right)
+		}
+	}
+	return t.combine(left, right)
+}
+
+// LazySegmentTree is a SegmentTree that can also apply an update to every value in a range in
+// O(log n) time, for example adding 5 to or assigning 0 to each of them.
+//
+// Updates are of type U. apply(u, x, n) returns the result of applying u to x, which is the
+// combination of n values. Applying an update to a combination must give the same result as
+// combining each of the updated values. For example, if combine is + and updates add a number to
+// each value, apply(u, x, n) is x + u*n. If combine is max, apply(u, x, n) is x + u.
+//
+// compose(newer, older) returns the update equivalent to applying older and then newer.
+type LazySegmentTree[T any, U any] struct {
+	identity T
+	combine  func(a, b T) T
+	apply    func(u U, x T, n int) T
+	compose  func(newer, older U) U
+	n        int
+	// tree[1] is the root, and the children of tree[i] are tree[2*i] and tree[2*i+1]. If
+	// hasPending[i], then pending[i] has been applied to tree[i] but not yet to its children.
+	tree       []T
+	pending    []U
+	hasPending []bool
+}
+
+// NewLazySegmentTree returns a LazySegmentTree holding the values in xs. It takes O(n) time.
+func NewLazySegmentTree[T any, U any](
+	xs []T,
+	identity T,
+	combine func(a, b T) T,
+	apply func(u U, x T, n int) T,
+	compose func(newer, older U) U,
+) *LazySegmentTree[T, U] {
+	n := len(xs)
+	size := 1
+	for size < 2*n {
+		size *= 2
+	}
+	t := &LazySegmentTree[T, U]{
+		identity:   identity,
+		combine:    combine,
+		apply:      apply,
+		compose:    compose,
+		n:          n,
+		tree:       make([]T, size),
+		pending:    make([]U, size),
+		hasPending: make([]bool, size),
+	}
+	if n > 0 {
+		t.build(xs, 1, 0, n)
+	}
+	return t
+}
+
+func (t *LazySegmentTree[T, U]) build(xs []T, node int, lo int, hi int) {
+	if hi-lo == 1 {
+		t.tree[node] = xs[lo]
+		return
+	}
+	mid := lo + (hi-lo)/2
+	t.build(xs, 2*node, lo, mid)
+	t.build(xs, 2*node+1, mid, hi)
+	t.tree[node] = t.combine(t.tree[2*node], t.tree[2*node+1])
+}
+
+// Len returns the number of values in t.
+func (t *LazySegmentTree[T, U]) Len() int {
+	return t.n
+}
+
+// Get returns the i-th value.
+func (t *LazySegmentTree[T, U]) Get(i int) T {
+	checkIndex(i, t.n)
+	return t.query(1, 0, t.n, i, i+1)
+}
+
+// Set sets the i-th value to x.
+func (t *LazySegmentTree[T, U]) Set(i int, x T) {
+	checkIndex(i, t.n)
+	t.set(1, 0, t.n, i, x)
+}
+
+// Query returns the combination of the values in [i, j), or identity if i == j.
+func (t *LazySegmentTree[T, U]) Query(i int, j int) T {
+	checkRange(i, j, t.n)
+	if i == j {
+		return t.identity
+	}
+	return t.query(1, 0, t.n, i, j)
+}
+
+// Update applies u to each of the values in [i, j).
+func (t *LazySegmentTree[T, U]) Update(i int, j int, u U) {
+	checkRange(i, j, t.n)
+	if i == j {
+		return
+	}
+	t.update(1, 0, t.n, i, j, u)
+}
+
+// applyTo applies u to node, which covers n values.
+func (t *LazySegmentTree[T, U]) applyTo(node int, n int, u U) {
+	t.tree[node] = t.apply(u, t.tree[node], n)
+	if 2*node < len(t.tree) {
+		if t.hasPending[node] {
+			t.pending[node] = t.compose(u, t.pending[node])
+		} else {
+			t.pending[node] = u
+			t.hasPending[node] = true
+		}
+	}
+}
+
+// push applies node's pending update to its children, which cover [lo, mid) and [mid, hi).
+func (t *LazySegmentTree[T, U]) push(node int, lo int, mid int, hi int) {
+	if !t.hasPending[node] {
+		return
+	}
+	t.applyTo(2*node, mid-lo, t.pending[node])
+	t.applyTo(2*node+1, hi-mid, t.pending[node])
+	var zero U
+	t.pending[node] = zero
+	t.hasPending[node] = false
+}
+
+// query returns the combination of the values in [i, j), which must overlap node's range [lo, hi).
+func (t *LazySegmentTree[T, U]) query(node int, lo int, hi int, i int, j int) T {
+	if i <= lo && hi <= j {
+		return t.tree[node]
+	}
+	mid := lo + (hi-lo)/2
+	t.push(node, lo, mid, hi)
+	if j <= mid {
+		return t.query(2*node, lo, mid, i, j)
+	} else if i >= mid {
+		return t.query(2*node+1, mid, hi, i, j)
+	}
+	return t.combine(t.query(2*node, lo, mid, i, j), t.query(2*node+1, mid, hi, i, j))
+}
+
+func (t *LazySegmentTree[T, U]) set(node int, lo int, hi int, i int, x T) {
+	if hi-lo == 1 {
+		t.tree[node] = x
+		return
+	}
+	mid := lo + (hi-lo)/2
+	t.push(node, lo, mid, hi)
+	if i < mid {
+		t.set(2*node, lo, mid, i, x)
+	} else {
+		t.set(2*node+1, mid, hi, i, x)
+	}
+	t.tree[node] = t.combine(t.tree[2*node], t.tree[2*node+1])
+}
+
+// update applies u to the values in [i, j), which must overlap node's range [lo, hi).
+func (t *LazySegmentTree[T, U]) update(node int, lo int, hi int, i int, j int, u U) {
+	if i <= lo && hi <= j {
+		t.applyTo(node, hi-lo, u)
+		return
+	}
+	mid := lo + (hi-lo)/2
+	t.push(node, lo, mid, hi)
+	if i < mid {
+		t.update(2*node, lo, mid, i, j, u)
+	}
+	if j > mid {
+		t.update(2*node+1, mid, hi, i, j, u)
+	}
+	t.tree[node] = t.combine(t.tree[2*node], t.tree[2*node+1])
+}
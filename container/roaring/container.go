@@ -0,0 +1,687 @@
+package roaring
+
+import (
+	"math/bits"
+	"sort"
+)
+
+const (
+	// Array containers hold at most this many values, beyond which a bitmap container is smaller.
+	maxArrayLen = 4096
+	// The number of words in a bitmap container.
+	bitmapWords = 1 << 16 / 64
+)
+
+type containerKind uint8
+
+const (
+	kindArray containerKind = iota + 1
+	kindBitmap
+	kindRun
+)
+
+// run is the inclusive range [start, last].
+type run struct {
+	start uint16
+	last  uint16
+}
+
+func (r run) len() int { return int(r.last) - int(r.start) + 1 }
+
+// container holds the low 16 bits of the members of a Bitmap that share the same high bits. It is
+// never empty.
+//
+// Depending on kind, only one of array, bitmap, or runs is used:
+//   - kindArray: array holds the members in ascending order. Used for sparse containers.
+//   - kindBitmap: bitmap has bitmapWords words, with bit i set if i is a member. Used for dense
+//     containers.
+//   - kindRun: runs holds the members as ascending, non-overlapping, non-adjacent ranges. Used for
+//     containers with long runs of consecutive members.
+type container struct {
+	kind   containerKind
+	n      int
+	array  []uint16
+	bitmap []uint64
+	runs   []run
+}
+
+func newArrayContainer(values []uint16) *container {
+	return &container{kind: kindArray, n: len(values), array: values}
+}
+
+func newBitmapContainer(words []uint64) *container {
+	n := 0
+	for _, word := range words {
+		n += bits.OnesCount64(word)
+	}
+	return &container{kind: kindBitmap, n: n, bitmap: words}
+}
+
+func newRunContainer(runs []run) *container {
+	n := 0
+	for _, r := range runs {
+		n += r.len()
+	}
+	return &container{kind: kindRun, n: n, runs: runs}
+}
+
+// fromValues returns an optimized container holding the ascending values, or nil if values is
+// empty.
+func fromValues(values []uint16) *container {
+	if len(values) == 0 {
+		return nil
+	}
+	var c *container
+	if len(values) <= maxArrayLen {
+		c = newArrayContainer(values)
+	} else {
+		words := make([]uint64, bitmapWords)
+		for _, x := range values {
+			words[x/64] |= 1 << (x % 64)
+		}
+		c = newBitmapContainer(words)
+	}
+	c.optimize()
+	return c
+}
+
+// fromWords returns an optimized container holding the set bits of words, or nil if there are none.
+func fromWords(words []uint64) *container {
+	c := newBitmapContainer(words)
+	if c.n == 0 {
+		return nil
+	}
+	c.optimize()
+	return c
+}
+
+// fromRuns returns an optimized container holding runs, or nil if there are none.
+func fromRuns(runs []run) *container {
+	if len(runs) == 0 {
+		return nil
+	}
+	c := newRunContainer(runs)
+	c.optimize()
+	return c
+}
+
+func (c *container) clone() *container {
+	return &container{
+		kind:   c.kind,
+		n:      c.n,
+		array:  append([]uint16(nil), c.array...),
+		bitmap: append([]uint64(nil), c.bitmap...),
+		runs:   append([]run(nil), c.runs...),
+	}
+}
+
+// searchRuns returns the index of the first run that ends at or after x.
+func searchRuns(runs []run, x uint16) int {
+	return sort.Search(len(runs), func(i int) bool { return runs[i].last >= x })
+}
+
+// searchArray returns the index of the first value in a that is >= x.
+func searchArray(a []uint16, x uint16) int {
+	return sort.Search(len(a), func(i int) bool { return a[i] >= x })
+}
+
+func (c *container) contains(x uint16) bool {
+	switch c.kind {
+	case kindArray:
+		i := searchArray(c.array, x)
+		return i < len(c.array) && c.array[i] == x
+	case kindBitmap:
+		return c.bitmap[x/64]&(1<<(x%64)) != 0
+	default:
+		i := searchRuns(c.runs, x)
+		return i < len(c.runs) && c.runs[i].start <= x
+	}
+}
+
+// add adds x to the container.
+func (c *container) add(x uint16) {
+	switch c.kind {
+	case kindArray:
+		i := searchArray(c.array, x)
+		if i < len(c.array) && c.array[i] == x {
+			return
+		}
+		if len(c.array) == maxArrayLen {
+			c.convert(kindBitmap)
+			c.add(x)
+			return
+		}
+		c.array = append(c.array, 0)
+		copy(c.array[i+1:], c.array[i:])
+		c.array[i] = x
+		c.n++
+	case kindBitmap:
+		if c.bitmap[x/64]&(1<<(x%64)) != 0 {
+			return
+		}
+		c.bitmap[x/64] |= 1 << (x % 64)
+		c.n++
+	default:
+		i := searchRuns(c.runs, x)
+		if i < len(c.runs) && c.runs[i].start <= x {
+			return
+		}
+		// Here, runs[i-1] (if any) ends before x and runs[i] (if any) starts after x.
+		joinsPrev := i > 0 && int(c.runs[i-1].last)+1 == int(x)
+		joinsNext := i < len(c.runs) && int(c.runs[i].start) == int(x)+1
+		switch {
+		case joinsPrev && joinsNext:
+			c.runs[i-1].last = c.runs[i].last
+			c.runs = append(c.runs[:i], c.runs[i+1:]...)
+		case joinsPrev:
+			c.runs[i-1].last = x
+		case joinsNext:
+			c.runs[i].start = x
+		default:
+			c.runs = append(c.runs, run{})
+			copy(c.runs[i+1:], c.runs[i:])
+			c.runs[i] = run{x, x}
+		}
+		c.n++
+		c.shrinkRuns()
+	}
+}
+
+// remove removes x from the container. The container may be left empty.
+func (c *container) remove(x uint16) {
+	switch c.kind {
+	case kindArray:
+		i := searchArray(c.array, x)
+		if i == len(c.array) || c.array[i] != x {
+			return
+		}
+		c.array = append(c.array[:i], c.array[i+1:]...)
+		c.n--
+	case kindBitmap:
+		if c.bitmap[x/64]&(1<<(x%64)) == 0 {
+			return
+		}
+		c.bitmap[x/64] &^= 1 << (x % 64)
+		c.n--
+		if c.n <= maxArrayLen {
+			c.convert(kindArray)
+		}
+	default:
+		i := searchRuns(c.runs, x)
+		if i == len(c.runs) || c.runs[i].start > x {
+			return
+		}
+		r := c.runs[i]
+		switch {
+		case r.start == x && r.last == x:
+			c.runs = append(c.runs[:i], c.runs[i+1:]...)
+		case r.start == x:
+			c.runs[i].start++
+		case r.last == x:
+			c.runs[i].last--
+		default:
+			c.runs = append(c.runs, run{})
+			copy(c.runs[i+1:], c.runs[i:])
+			c.runs[i].last = x - 1
+			c.runs[i+1].start = x + 1
+		}
+		c.n--
+		c.shrinkRuns()
+	}
+}
+
+// addRange adds [first, last] to the container.
+func (c *container) addRange(first, last uint16) {
+	runs := c.toRuns()
+	i := searchRuns(runs, first)
+	// runs[i:j] overlap or are adjacent to [first, last] and get merged with it.
+	if i > 0 && int(runs[i-1].last)+1 == int(first) {
+		i--
+	}
+	j := i
+	for j < len(runs) && int(runs[j].start) <= int(last)+1 {
+		j++
+	}
+	merged := run{first, last}
+	if i < j {
+		if runs[i].start < merged.start {
+			merged.start = runs[i].start
+		}
+		if runs[j-1].last > merged.last {
+			merged.last = runs[j-1].last
+		}
+	}
+	out := make([]run, 0, len(runs)-(j-i)+1)
+	out = append(out, runs[:i]...)
+	out = append(out, merged)
+	out = append(out, runs[j:]...)
+	*c = *newRunContainer(out)
+	c.optimize()
+}
+
+// removeRange removes [first, last] from the container. The container may be left empty.
+func (c *container) removeRange(first, last uint16) {
+	runs := c.toRuns()
+	out := make([]run, 0, len(runs)+1)
+	for _, r := range runs {
+		if r.last < first || r.start > last {
+			out = append(out, r)
+			continue
+		}
+		if r.start < first {
+			out = append(out, run{r.start, first - 1})
+		}
+		if r.last > last {
+			out = append(out, run{last + 1, r.last})
+		}
+	}
+	*c = *newRunContainer(out)
+	if c.n > 0 {
+		c.optimize()
+	}
+}
+
+// rank returns the number of members <= x.
+func (c *container) rank(x uint16) int {
+	switch c.kind {
+	case kindArray:
+		i := searchArray(c.array, x)
+		if i < len(c.array) && c.array[i] == x {
+			i++
+		}
+		return i
+	case kindBitmap:
+		n := 0
+		for _, word := range c.bitmap[:x/64] {
+			n += bits.OnesCount64(word)
+		}
+		// When x%64 == 63 the shift produces 0, so mask is all ones.
+		mask := uint64(1)<<(x%64+1) - 1
+		return n + bits.OnesCount64(c.bitmap[x/64]&mask)
+	default:
+		n := 0
+		for _, r := range c.runs {
+			if r.start > x {
+				break
+			}
+			if r.last >= x {
+				return n + int(x) - int(r.start) + 1
+			}
+			n += r.len()
+		}
+		return n
+	}
+}
+
+// selectAt returns the i-th smallest member, 0-indexed. i must be less than c.n.
+func (c *container) selectAt(i int) uint16 {
+	switch c.kind {
+	case kindArray:
+		return c.array[i]
+	case kindBitmap:
+		for w, word := range c.bitmap {
+			n := bits.OnesCount64(word)
+			if i < n {
+				for ; i > 0; i-- {
+					// Clear the lowest set bit.
+					word &= word - 1
+				}
+				return uint16(w*64 + bits.TrailingZeros64(word))
+			}
+			i -= n
+		}
+	default:
+		for _, r := range c.runs {
+			if i < r.len() {
+				return r.start + uint16(i)
+			}
+			i -= r.len()
+		}
+	}
+	panic("selectAt out of range")
+}
+
+// countRuns returns the number of runs the container would have as a run container.
+func (c *container) countRuns() int {
+	switch c.kind {
+	case kindArray:
+		n := 0
+		for i, x := range c.array {
+			if i == 0 || c.array[i-1]+1 != x {
+				n++
+			}
+		}
+		return n
+	case kindBitmap:
+		n := 0
+		for i, word := range c.bitmap {
+			// Count the 0->1 transitions going from low bits to high bits, carrying over the top
+			// bit of the previous word.
+			var carry uint64
+			if i > 0 {
+				carry = c.bitmap[i-1] >> 63
+			}
+			n += bits.OnesCount64(word &^ (word<<1 | carry))
+		}
+		return n
+	default:
+		return len(c.runs)
+	}
+}
+
+// optimize converts the container to whichever kind takes the least memory.
+func (c *container) optimize() {
+	c.convert(c.bestKind(c.countRuns()))
+}
+
+func (c *container) bestKind(nRuns int) containerKind {
+	best := kindBitmap
+	bestSize := bitmapWords * 8
+	if c.n <= maxArrayLen && 2*c.n <= bestSize {
+		best = kindArray
+		bestSize = 2 * c.n
+	}
+	if 4*nRuns < bestSize {
+		best = kindRun
+	}
+	return best
+}
+
+// shrinkRuns converts a run container that has grown too fragmented into an array or bitmap.
+func (c *container) shrinkRuns() {
+	if c.kind == kindRun && len(c.runs) > 0 {
+		if kind := c.bestKind(len(c.runs)); kind != kindRun {
+			c.convert(kind)
+		}
+	}
+}
+
+func (c *container) convert(kind containerKind) {
+	if c.kind == kind {
+		return
+	}
+	switch kind {
+	case kindArray:
+		*c = *newArrayContainer(c.toArray())
+	case kindBitmap:
+		*c = *newBitmapContainer(c.toWords())
+	default:
+		*c = *newRunContainer(c.toRuns())
+	}
+}
+
+// toArray returns the members of c in ascending order. It may alias c.
+func (c *container) toArray() []uint16 {
+	if c.kind == kindArray {
+		return c.array
+	}
+	out := make([]uint16, 0, c.n)
+	iter := c.iterate()
+	for {
+		x, ok := iter.next()
+		if !ok {
+			break
+		}
+		out = append(out, x)
+	}
+	return out
+}
+
+// toWords returns the members of c as a bitmap. It never aliases c.
+func (c *container) toWords() []uint64 {
+	switch c.kind {
+	case kindBitmap:
+		return append([]uint64(nil), c.bitmap...)
+	case kindArray:
+		words := make([]uint64, bitmapWords)
+		for _, x := range c.array {
+			words[x/64] |= 1 << (x % 64)
+		}
+		return words
+	default:
+		words := make([]uint64, bitmapWords)
+		for _, r := range c.runs {
+			setRange(words, int(r.start), int(r.last))
+		}
+		return words
+	}
+}
+
+// toRuns returns the members of c as runs. It may alias c.
+func (c *container) toRuns() []run {
+	if c.kind == kindRun {
+		return c.runs
+	}
+	out := make([]run, 0, c.countRuns())
+	iter := c.iterate()
+	for {
+		x, ok := iter.next()
+		if !ok {
+			break
+		}
+		if len(out) > 0 && int(out[len(out)-1].last)+1 == int(x) {
+			out[len(out)-1].last = x
+		} else {
+			out = append(out, run{x, x})
+		}
+	}
+	return out
+}
+
+// setRange sets bits [first, last] of words.
+func setRange(words []uint64, first, last int) {
+	for first <= last {
+		w := first / 64
+		lo := first % 64
+		hi := 63
+		if last/64 == w {
+			hi = last % 64
+		}
+		mask := ^uint64(0) >> (63 - (hi - lo)) << lo
+		words[w] |= mask
+		first = w*64 + 64
+	}
+}
+
+type containerIterator struct {
+	c *container
+	// Index into array, bitmap, or runs.
+	i int
+	// kindBitmap: the bits of bitmap[i] not yet yielded.
+	word uint64
+	// kindRun: the next value to yield from runs[i].
+	x uint16
+}
+
+func (c *container) iterate() containerIterator {
+	iter := containerIterator{c: c}
+	switch c.kind {
+	case kindBitmap:
+		iter.word = c.bitmap[0]
+	case kindRun:
+		if len(c.runs) > 0 {
+			iter.x = c.runs[0].start
+		}
+	}
+	return iter
+}
+
+func (iter *containerIterator) next() (uint16, bool) {
+	switch iter.c.kind {
+	case kindArray:
+		if iter.i >= len(iter.c.array) {
+			return 0, false
+		}
+		x := iter.c.array[iter.i]
+		iter.i++
+		return x, true
+	case kindBitmap:
+		for iter.word == 0 {
+			iter.i++
+			if iter.i >= len(iter.c.bitmap) {
+				return 0, false
+			}
+			iter.word = iter.c.bitmap[iter.i]
+		}
+		x := uint16(iter.i*64 + bits.TrailingZeros64(iter.word))
+		iter.word &= iter.word - 1
+		return x, true
+	default:
+		if iter.i >= len(iter.c.runs) {
+			return 0, false
+		}
+		x := iter.x
+		if x == iter.c.runs[iter.i].last {
+			iter.i++
+			if iter.i < len(iter.c.runs) {
+				iter.x = iter.c.runs[iter.i].start
+			}
+		} else {
+			iter.x++
+		}
+		return x, true
+	}
+}
+
+// and returns a container holding the members of both a and b, or nil if there are none.
+func and(a, b *container) *container {
+	if a.kind != kindArray && b.kind == kindArray {
+		a, b = b, a
+	}
+	switch {
+	case a.kind == kindArray:
+		out := make([]uint16, 0, a.n)
+		for _, x := range a.array {
+			if b.contains(x) {
+				out = append(out, x)
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return newArrayContainer(out)
+	case a.kind == kindRun && b.kind == kindRun:
+		var out []run
+		i, j := 0, 0
+		for i < len(a.runs) && j < len(b.runs) {
+			ra, rb := a.runs[i], b.runs[j]
+			start, last := ra.start, ra.last
+			if rb.start > start {
+				start = rb.start
+			}
+			if rb.last < last {
+				last = rb.last
+			}
+			if start <= last {
+				out = append(out, run{start, last})
+			}
+			if ra.last < rb.last {
+				i++
+			} else {
+				j++
+			}
+		}
+		return fromRuns(out)
+	default:
+		words := a.toWords()
+		if b.kind == kindBitmap {
+			for i, word := range b.bitmap {
+				words[i] &= word
+			}
+		} else {
+			other := b.toWords()
+			for i, word := range other {
+				words[i] &= word
+			}
+		}
+		return fromWords(words)
+	}
+}
+
+// or returns a container holding the members of either a or b.
+func or(a, b *container) *container {
+	switch {
+	case a.kind == kindArray && b.kind == kindArray:
+		out := make([]uint16, 0, a.n+b.n)
+		i, j := 0, 0
+		for i < len(a.array) && j < len(b.array) {
+			switch {
+			case a.array[i] < b.array[j]:
+				out = append(out, a.array[i])
+				i++
+			case a.array[i] > b.array[j]:
+				out = append(out, b.array[j])
+				j++
+			default:
+				out = append(out, a.array[i])
+				i++
+				j++
+			}
+		}
+		out = append(out, a.array[i:]...)
+		out = append(out, b.array[j:]...)
+		return fromValues(out)
+	case a.kind == kindRun && b.kind == kindRun:
+		out := make([]run, 0, len(a.runs)+len(b.runs))
+		i, j := 0, 0
+		for i < len(a.runs) || j < len(b.runs) {
+			var r run
+			if j == len(b.runs) || (i < len(a.runs) && a.runs[i].start < b.runs[j].start) {
+				r = a.runs[i]
+				i++
+			} else {
+				r = b.runs[j]
+				j++
+			}
+			if len(out) > 0 && int(out[len(out)-1].last)+1 >= int(r.start) {
+				if r.last > out[len(out)-1].last {
+					out[len(out)-1].last = r.last
+				}
+			} else {
+				out = append(out, r)
+			}
+		}
+		return fromRuns(out)
+	default:
+		words := a.toWords()
+		other := b.toWords()
+		for i, word := range other {
+			words[i] |= word
+		}
+		return fromWords(words)
+	}
+}
+
+// xor returns a container holding the members of exactly one of a and b, or nil if there are
+// none.
+func xor(a, b *container) *container {
+	words := a.toWords()
+	other := b.toWords()
+	for i, word := range other {
+		words[i] ^= word
+	}
+	return fromWords(words)
+}
+
+// andNot returns a container holding the members of a that are not in b, or nil if there are
+// none.
+func andNot(a, b *container) *container {
+	if a.kind == kindArray {
+		out := make([]uint16, 0, a.n)
+		for _, x := range a.array {
+			if !b.contains(x) {
+				out = append(out, x)
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return newArrayContainer(out)
+	}
+	words := a.toWords()
+	other := b.toWords()
+	for i, word := range other {
+		words[i] &^= word
+	}
+	return fromWords(words)
+}
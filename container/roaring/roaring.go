@@ -0,0 +1,483 @@
+// Package roaring contains a compressed bitmap for sets of unsigned integers.
+package roaring
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/bradenaw/juniper/iterator"
+)
+
+// Bitmap is a set of unsigned integers using the Roaring scheme (https://roaringbitmap.org/).
+//
+// Members are grouped by their high bits into blocks of 65536 integers, and each non-empty block is
+// stored in whichever of three representations is smallest for its contents: a sorted array for
+// sparse blocks, a bitmap for dense blocks, or a list of ranges for blocks with long runs of
+// consecutive members. This makes Bitmap compact for sets that are sparse across a huge range but
+// dense or clustered locally, such as posting lists of IDs.
+//
+// Contains, Add, and Remove take O(log b) time, where b is the number of non-empty blocks, plus
+// O(4096) in the worst case to change a block's representation. Adding members in ascending order
+// is fastest.
+//
+// The zero value is an empty set ready to use. Bitmap is not safe for concurrent use.
+type Bitmap[T uint32 | uint64] struct {
+	// Parallel slices of the high bits of each block, ascending, and the block's low bits.
+	keys       []uint64
+	containers []*container
+}
+
+// New returns a Bitmap containing the given integers.
+func New[T uint32 | uint64](items ...T) *Bitmap[T] {
+	b := &Bitmap[T]{}
+	for _, x := range items {
+		b.Add(x)
+	}
+	return b
+}
+
+func split[T uint32 | uint64](x T) (uint64, uint16) {
+	return uint64(x) >> 16, uint16(x)
+}
+
+func join[T uint32 | uint64](key uint64, low uint16) T {
+	return T(key<<16 | uint64(low))
+}
+
+// search returns the index of the first key >= key, and whether it is equal to key.
+func (b *Bitmap[T]) search(key uint64) (int, bool) {
+	i := sort.Search(len(b.keys), func(i int) bool { return b.keys[i] >= key })
+	return i, i < len(b.keys) && b.keys[i] == key
+}
+
+// Clone returns a copy of b.
+func (b *Bitmap[T]) Clone() *Bitmap[T] {
+	out := &Bitmap[T]{
+		keys:       append([]uint64(nil), b.keys...),
+		containers: make([]*container, len(b.containers)),
+	}
+	for i, c := range b.containers {
+		out.containers[i] = c.clone()
+	}
+	return out
+}
+
+// Count returns the number of integers in the set.
+func (b *Bitmap[T]) Count() uint64 {
+	n := uint64(0)
+	for _, c := range b.containers {
+		n += uint64(c.n)
+	}
+	return n
+}
+
+// Contains returns true if x is in the set.
+func (b *Bitmap[T]) Contains(x T) bool {
+	key, low := split(x)
+	i, ok := b.search(key)
+	return ok && b.containers[i].contains(low)
+}
+
+// Add adds x to the set.
+func (b *Bitmap[T]) Add(x T) {
+	key, low := split(x)
+	i, ok := b.search(key)
+	if !ok {
+		b.insert(i, key, newArrayContainer([]uint16{low}))
+		return
+	}
+	b.containers[i].add(low)
+}
+
+// Remove removes x from the set.
+func (b *Bitmap[T]) Remove(x T) {
+	key, low := split(x)
+	i, ok := b.search(key)
+	if !ok {
+		return
+	}
+	b.containers[i].remove(low)
+	if b.containers[i].n == 0 {
+		b.delete(i)
+	}
+}
+
+// AddRange adds all of the integers in [first, last] to the set.
+//
+// AddRange takes time proportional to the number of blocks of 65536 integers that the range covers.
+func (b *Bitmap[T]) AddRange(first, last T) {
+	if first > last {
+		return
+	}
+	firstKey, firstLow := split(first)
+	lastKey, lastLow := split(last)
+	i, _ := b.search(firstKey)
+	for key := firstKey; ; key++ {
+		low, high := uint16(0), uint16(0xFFFF)
+		if key == firstKey {
+			low = firstLow
+		}
+		if key == lastKey {
+			high = lastLow
+		}
+		if i < len(b.keys) && b.keys[i] == key {
+			b.containers[i].addRange(low, high)
+		} else {
+			b.insert(i, key, newRunContainer([]run{{low, high}}))
+		}
+		i++
+		if key == lastKey {
+			break
+		}
+	}
+}
+
+// RemoveRange removes all of the integers in [first, last] from the set.
+//
+// RemoveRange takes time proportional to the number of non-empty blocks of 65536 integers that the
+// range covers.
+func (b *Bitmap[T]) RemoveRange(first, last T) {
+	if first > last {
+		return
+	}
+	firstKey, firstLow := split(first)
+	lastKey, lastLow := split(last)
+	i, _ := b.search(firstKey)
+	for i < len(b.keys) && b.keys[i] <= lastKey {
+		key := b.keys[i]
+		low, high := uint16(0), uint16(0xFFFF)
+		if key == firstKey {
+			low = firstLow
+		}
+		if key == lastKey {
+			high = lastLow
+		}
+		b.containers[i].removeRange(low, high)
+		if b.containers[i].n == 0 {
+			b.delete(i)
+		} else {
+			i++
+		}
+	}
+}
+
+// Rank returns the number of members of the set that are <= x.
+func (b *Bitmap[T]) Rank(x T) uint64 {
+	key, low := split(x)
+	i, ok := b.search(key)
+	n := uint64(0)
+	for _, c := range b.containers[:i] {
+		n += uint64(c.n)
+	}
+	if ok {
+		n += uint64(b.containers[i].rank(low))
+	}
+	return n
+}
+
+// Select returns the i-th smallest member of the set, counting from 0. If the set has i or fewer
+// members, returns false in the second return.
+func (b *Bitmap[T]) Select(i uint64) (T, bool) {
+	for j, c := range b.containers {
+		if i < uint64(c.n) {
+			return join[T](b.keys[j], c.selectAt(int(i))), true
+		}
+		i -= uint64(c.n)
+	}
+	return 0, false
+}
+
+// Optimize converts every block to its smallest representation.
+//
+// Blocks are converted automatically by AddRange, RemoveRange, and the binary operations. However,
+// Add and Remove only convert blocks when they must, so a set built with Add may have blocks that
+// would be smaller as ranges.
+func (b *Bitmap[T]) Optimize() {
+	for _, c := range b.containers {
+		c.optimize()
+	}
+}
+
+// Iterate returns an iterator over the members of the set in ascending order.
+//
+// The iterator is invalidated if the set is modified.
+func (b *Bitmap[T]) Iterate() iterator.Iterator[T] {
+	iter := &bitmapIterator[T]{b: b}
+	if len(b.containers) > 0 {
+		iter.inner = b.containers[0].iterate()
+	}
+	return iter
+}
+
+type bitmapIterator[T uint32 | uint64] struct {
+	b     *Bitmap[T]
+	i     int
+	inner containerIterator
+}
+
+func (iter *bitmapIterator[T]) Next() (T, bool) {
+	for iter.i < len(iter.b.containers) {
+		low, ok := iter.inner.next()
+		if ok {
+			return join[T](iter.b.keys[iter.i], low), true
+		}
+		iter.i++
+		if iter.i < len(iter.b.containers) {
+			iter.inner = iter.b.containers[iter.i].iterate()
+		}
+	}
+	return 0, false
+}
+
+// Equal returns true if b and other have the same members.
+func (b *Bitmap[T]) Equal(other *Bitmap[T]) bool {
+	if len(b.keys) != len(other.keys) {
+		return false
+	}
+	for i := range b.keys {
+		if b.keys[i] != other.keys[i] || b.containers[i].n != other.containers[i].n {
+			return false
+		}
+		iterA := b.containers[i].iterate()
+		iterB := other.containers[i].iterate()
+		for {
+			x, ok := iterA.next()
+			y, _ := iterB.next()
+			if !ok {
+				break
+			}
+			if x != y {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// And removes all members of b that are not in other.
+func (b *Bitmap[T]) And(other *Bitmap[T]) { *b = *And(b, other) }
+
+// Or adds all members of other to b.
+func (b *Bitmap[T]) Or(other *Bitmap[T]) { *b = *Or(b, other) }
+
+// Xor makes b contain the integers that are in exactly one of b and other.
+func (b *Bitmap[T]) Xor(other *Bitmap[T]) { *b = *Xor(b, other) }
+
+// AndNot removes all members of other from b.
+func (b *Bitmap[T]) AndNot(other *Bitmap[T]) { *b = *AndNot(b, other) }
+
+// And returns a new Bitmap containing the integers that are in both a and b.
+func And[T uint32 | uint64](a, b *Bitmap[T]) *Bitmap[T] {
+	return merge(a, b, and, false, false)
+}
+
+// Or returns a new Bitmap containing the integers that are in either a or b.
+func Or[T uint32 | uint64](a, b *Bitmap[T]) *Bitmap[T] {
+	return merge(a, b, or, true, true)
+}
+
+// Xor returns a new Bitmap containing the integers that are in exactly one of a and b.
+func Xor[T uint32 | uint64](a, b *Bitmap[T]) *Bitmap[T] {
+	return merge(a, b, xor, true, true)
+}
+
+// AndNot returns a new Bitmap containing the integers that are in a but not in b.
+func AndNot[T uint32 | uint64](a, b *Bitmap[T]) *Bitmap[T] {
+	return merge(a, b, andNot, true, false)
+}
+
+// merge combines the blocks of a and b. Blocks present in both are combined with f, blocks only in
+// a are kept if keepA, and blocks only in b are kept if keepB.
+func merge[T uint32 | uint64](
+	a, b *Bitmap[T],
+	f func(a, b *container) *container,
+	keepA bool,
+	keepB bool,
+) *Bitmap[T] {
+	out := &Bitmap[T]{}
+	i, j := 0, 0
+	for i < len(a.keys) || j < len(b.keys) {
+		switch {
+		case j == len(b.keys) || (i < len(a.keys) && a.keys[i] < b.keys[j]):
+			if keepA {
+				out.keys = append(out.keys, a.keys[i])
+				out.containers = append(out.containers, a.containers[i].clone())
+			}
+			i++
+		case i == len(a.keys) || b.keys[j] < a.keys[i]:
+			if keepB {
+				out.keys = append(out.keys, b.keys[j])
+				out.containers = append(out.containers, b.containers[j].clone())
+			}
+			j++
+		default:
+			c := f(a.containers[i], b.containers[j])
+			if c != nil {
+				out.keys = append(out.keys, a.keys[i])
+				out.containers = append(out.containers, c)
+			}
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+func (b *Bitmap[T]) insert(i int, key uint64, c *container) {
+	b.keys = append(b.keys, 0)
+	copy(b.keys[i+1:], b.keys[i:])
+	b.keys[i] = key
+	b.containers = append(b.containers, nil)
+	copy(b.containers[i+1:], b.containers[i:])
+	b.containers[i] = c
+}
+
+func (b *Bitmap[T]) delete(i int) {
+	b.keys = append(b.keys[:i], b.keys[i+1:]...)
+	copy(b.containers[i:], b.containers[i+1:])
+	b.containers[len(b.containers)-1] = nil
+	b.containers = b.containers[:len(b.containers)-1]
+}
+
+const serialVersion = 1
+
+var errInvalidData = errors.New("invalid roaring bitmap data")
+
+// MarshalBinary encodes the set in a compact binary format that can be decoded by UnmarshalBinary.
+// The format is specific to this package.
+func (b *Bitmap[T]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	var scratch [binary.MaxVarintLen64]byte
+	writeUvarint := func(x uint64) {
+		buf.Write(scratch[:binary.PutUvarint(scratch[:], x)])
+	}
+
+	buf.WriteByte(serialVersion)
+	writeUvarint(uint64(len(b.keys)))
+	prevKey := uint64(0)
+	for i, c := range b.containers {
+		// Keys are ascending, so delta-encode them to keep them small.
+		writeUvarint(b.keys[i] - prevKey)
+		prevKey = b.keys[i]
+		buf.WriteByte(byte(c.kind))
+		switch c.kind {
+		case kindArray:
+			writeUvarint(uint64(len(c.array)))
+			_ = binary.Write(&buf, binary.LittleEndian, c.array)
+		case kindBitmap:
+			_ = binary.Write(&buf, binary.LittleEndian, c.bitmap)
+		case kindRun:
+			writeUvarint(uint64(len(c.runs)))
+			for _, r := range c.runs {
+				_ = binary.Write(&buf, binary.LittleEndian, r.start)
+				_ = binary.Write(&buf, binary.LittleEndian, r.last)
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the contents of b with the set encoded in data by MarshalBinary.
+func (b *Bitmap[T]) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	version, err := r.ReadByte()
+	if err != nil {
+		return errInvalidData
+	}
+	if version != serialVersion {
+		return errors.New("unknown roaring bitmap data version")
+	}
+	nContainers, err := binary.ReadUvarint(r)
+	// Every container takes at least 3 bytes, which bounds the allocation below.
+	if err != nil || nContainers > uint64(r.Len())/3 {
+		return errInvalidData
+	}
+	out := Bitmap[T]{
+		keys:       make([]uint64, 0, nContainers),
+		containers: make([]*container, 0, nContainers),
+	}
+	for i := uint64(0); i < nContainers; i++ {
+		delta, err := binary.ReadUvarint(r)
+		if err != nil {
+			return errInvalidData
+		}
+		key := delta
+		if i > 0 {
+			if delta == 0 {
+				return errInvalidData
+			}
+			key = out.keys[len(out.keys)-1] + delta
+			if key < delta {
+				return errInvalidData
+			}
+		}
+		// Keys must fit in T after shifting.
+		if uint64(T(key<<16))>>16 != key {
+			return errInvalidData
+		}
+		c, err := readContainer(r)
+		if err != nil {
+			return err
+		}
+		out.keys = append(out.keys, key)
+		out.containers = append(out.containers, c)
+	}
+	if r.Len() != 0 {
+		return errInvalidData
+	}
+	*b = out
+	return nil
+}
+
+func readContainer(r *bytes.Reader) (*container, error) {
+	kind, err := r.ReadByte()
+	if err != nil {
+		return nil, errInvalidData
+	}
+	switch containerKind(kind) {
+	case kindArray:
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n == 0 || n > maxArrayLen || n*2 > uint64(r.Len()) {
+			return nil, errInvalidData
+		}
+		array := make([]uint16, n)
+		_ = binary.Read(r, binary.LittleEndian, array)
+		for i := 1; i < len(array); i++ {
+			if array[i-1] >= array[i] {
+				return nil, errInvalidData
+			}
+		}
+		return newArrayContainer(array), nil
+	case kindBitmap:
+		words := make([]uint64, bitmapWords)
+		err := binary.Read(r, binary.LittleEndian, words)
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			return nil, errInvalidData
+		}
+		c := newBitmapContainer(words)
+		if c.n == 0 {
+			return nil, errInvalidData
+		}
+		return c, nil
+	case kindRun:
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n == 0 || n*4 > uint64(r.Len()) {
+			return nil, errInvalidData
+		}
+		runs := make([]run, n)
+		for i := range runs {
+			_ = binary.Read(r, binary.LittleEndian, &runs[i].start)
+			_ = binary.Read(r, binary.LittleEndian, &runs[i].last)
+			if runs[i].start > runs[i].last ||
+				(i > 0 && int(runs[i-1].last)+1 >= int(runs[i].start)) {
+				return nil, errInvalidData
+			}
+		}
+		return newRunContainer(runs), nil
+	default:
+		return nil, errInvalidData
+	}
+}
@@ -0,0 +1,271 @@
+package roaring
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/bradenaw/juniper/container/bitset"
+	"github.com/bradenaw/juniper/internal/fuzz"
+	"github.com/bradenaw/juniper/internal/require2"
+	"github.com/bradenaw/juniper/iterator"
+)
+
+func FuzzBitmap(f *testing.F) {
+	// Members are confined to the first three blocks so that blocks fill up enough to change
+	// representation.
+	const maxMember = 3*(1<<16) - 1
+	member := func(x int) uint32 { return uint32(uint(x) % (maxMember + 1)) }
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		// Two sets so that the binary operations have something to work with.
+		sets := [2]*Bitmap[uint32]{{}, New[uint32]()}
+		oracles := [2]*bitset.Bitset{{}, {}}
+
+		members := func(o *bitset.Bitset) []uint32 {
+			return iterator.Collect(iterator.Map(o.Iterate(), func(x int) uint32 { return uint32(x) }))
+		}
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				for j := range sets {
+					expected := members(oracles[j])
+					require2.SlicesEqual(t, expected, iterator.Collect(sets[j].Iterate()))
+					require2.Equal(t, uint64(len(expected)), sets[j].Count())
+					for i, c := range sets[j].containers {
+						require2.Greater(t, c.n, 0)
+						if i > 0 {
+							require2.Less(t, sets[j].keys[i-1], sets[j].keys[i])
+						}
+					}
+
+					data, err := sets[j].MarshalBinary()
+					require2.NoError(t, err)
+					var decoded Bitmap[uint32]
+					require2.NoError(t, decoded.UnmarshalBinary(data))
+					require2.True(t, decoded.Equal(sets[j]))
+				}
+			},
+			func(which bool, xi int) {
+				j := boolIndex(which)
+				x := member(xi)
+				t.Logf("sets[%d].Add(%d)", j, x)
+				sets[j].Add(x)
+				oracles[j].Set(int(x))
+			},
+			func(which bool, xi int) {
+				j := boolIndex(which)
+				x := member(xi)
+				t.Logf("sets[%d].Remove(%d)", j, x)
+				sets[j].Remove(x)
+				oracles[j].Clear(int(x))
+			},
+			func(which bool, xi int) {
+				j := boolIndex(which)
+				x := member(xi)
+				t.Logf("sets[%d].Contains(%d)", j, x)
+				require2.Equal(t, oracles[j].Test(int(x)), sets[j].Contains(x))
+			},
+			func(which bool, firstI int, lastI int) {
+				j := boolIndex(which)
+				first := member(firstI)
+				last := member(lastI)
+				t.Logf("sets[%d].AddRange(%d, %d)", j, first, last)
+				sets[j].AddRange(first, last)
+				for x := int(first); x <= int(last); x++ {
+					oracles[j].Set(x)
+				}
+			},
+			func(which bool, firstI int, lastI int) {
+				j := boolIndex(which)
+				first := member(firstI)
+				last := member(lastI)
+				t.Logf("sets[%d].RemoveRange(%d, %d)", j, first, last)
+				sets[j].RemoveRange(first, last)
+				for x := int(first); x <= int(last); x++ {
+					oracles[j].Clear(x)
+				}
+			},
+			func(which bool, xi int) {
+				j := boolIndex(which)
+				x := member(xi)
+				expected := uint64(0)
+				for _, y := range members(oracles[j]) {
+					if y <= x {
+						expected++
+					}
+				}
+				t.Logf("sets[%d].Rank(%d)", j, x)
+				require2.Equal(t, expected, sets[j].Rank(x))
+			},
+			func(which bool, ii int) {
+				i := uint32(ii)
+				j := boolIndex(which)
+				expected := members(oracles[j])
+				if len(expected) > 0 && i%2 == 0 {
+					// Pick an index in range more often than not.
+					i %= uint32(len(expected))
+				}
+				t.Logf("sets[%d].Select(%d)", j, i)
+				x, ok := sets[j].Select(uint64(i))
+				require2.Equal(t, int(i) < len(expected), ok)
+				if ok {
+					require2.Equal(t, expected[i], x)
+				}
+			},
+			func(which bool) {
+				j := boolIndex(which)
+				t.Logf("sets[%d].Optimize()", j)
+				sets[j].Optimize()
+			},
+			func(which bool, op byte, inPlace bool) {
+				j := boolIndex(which)
+				a, b := sets[j], sets[1-j]
+				expected := oracles[j].Clone()
+				var result *Bitmap[uint32]
+				switch op % 4 {
+				case 0:
+					t.Logf("sets[%d].And(sets[%d]), inPlace=%t", j, 1-j, inPlace)
+					expected.And(oracles[1-j])
+					if inPlace {
+						a.And(b)
+					} else {
+						result = And(a, b)
+					}
+				case 1:
+					t.Logf("sets[%d].Or(sets[%d]), inPlace=%t", j, 1-j, inPlace)
+					expected.Or(oracles[1-j])
+					if inPlace {
+						a.Or(b)
+					} else {
+						result = Or(a, b)
+					}
+				case 2:
+					t.Logf("sets[%d].Xor(sets[%d]), inPlace=%t", j, 1-j, inPlace)
+					expected.Xor(oracles[1-j])
+					if inPlace {
+						a.Xor(b)
+					} else {
+						result = Xor(a, b)
+					}
+				case 3:
+					t.Logf("sets[%d].AndNot(sets[%d]), inPlace=%t", j, 1-j, inPlace)
+					expected.AndNot(oracles[1-j])
+					if inPlace {
+						a.AndNot(b)
+					} else {
+						result = AndNot(a, b)
+					}
+				}
+				if inPlace {
+					oracles[j] = expected
+				} else {
+					require2.SlicesEqual(t, members(expected), iterator.Collect(result.Iterate()))
+				}
+			},
+		)
+	})
+}
+
+func boolIndex(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestBitmapContainerKinds(t *testing.T) {
+	var b Bitmap[uint32]
+	for x := uint32(0); x < 100; x++ {
+		b.Add(x * 3)
+	}
+	require2.Equal(t, kindArray, b.containers[0].kind)
+
+	for x := uint32(0); x < 10000; x++ {
+		b.Add(x * 3)
+	}
+	require2.Equal(t, kindBitmap, b.containers[0].kind)
+
+	b.AddRange(0, 1<<16-1)
+	require2.Equal(t, kindRun, b.containers[0].kind)
+	require2.Equal(t, 1, len(b.containers[0].runs))
+	require2.Equal(t, uint64(1<<16), b.Count())
+
+	var c Bitmap[uint32]
+	for x := uint32(0); x < 1000; x++ {
+		c.Add(x)
+	}
+	require2.Equal(t, kindArray, c.containers[0].kind)
+	c.Optimize()
+	require2.Equal(t, kindRun, c.containers[0].kind)
+}
+
+func TestBitmap64(t *testing.T) {
+	var b Bitmap[uint64]
+	b.Add(5)
+	b.Add(1 << 40)
+	b.AddRange(1<<50-10, 1<<50+10)
+	b.Add(math.MaxUint64)
+
+	require2.Equal(t, uint64(24), b.Count())
+	require2.True(t, b.Contains(1<<40))
+	require2.True(t, b.Contains(1<<50))
+	require2.True(t, !b.Contains(1<<50+11))
+	require2.Equal(t, uint64(2), b.Rank(1<<50-11))
+	require2.Equal(t, uint64(13), b.Rank(1<<50))
+
+	x, ok := b.Select(2)
+	require2.True(t, ok)
+	require2.Equal(t, uint64(1<<50-10), x)
+	x, ok = b.Select(23)
+	require2.True(t, ok)
+	require2.Equal(t, uint64(math.MaxUint64), x)
+	_, ok = b.Select(24)
+	require2.True(t, !ok)
+
+	data, err := b.MarshalBinary()
+	require2.NoError(t, err)
+	var decoded Bitmap[uint64]
+	require2.NoError(t, decoded.UnmarshalBinary(data))
+	require2.True(t, decoded.Equal(&b))
+
+	// Keys from a 64-bit bitmap don't fit in a 32-bit one.
+	var decoded32 Bitmap[uint32]
+	require2.Error(t, decoded32.UnmarshalBinary(data))
+
+	b.RemoveRange(6, math.MaxUint64-1)
+	require2.SlicesEqual(t, []uint64{5, math.MaxUint64}, iterator.Collect(b.Iterate()))
+}
+
+func TestUnmarshalBinaryInvalid(t *testing.T) {
+	var b Bitmap[uint32]
+	b.AddRange(10, 20)
+	b.Add(100000)
+	data, err := b.MarshalBinary()
+	require2.NoError(t, err)
+
+	for i := 0; i < len(data); i++ {
+		var decoded Bitmap[uint32]
+		require2.Error(t, decoded.UnmarshalBinary(data[:i]))
+	}
+	require2.Error(t, b.UnmarshalBinary(append(data, 0)))
+}
+
+func ExampleBitmap() {
+	a := New[uint32](1, 2, 3, 1_000_000)
+	a.AddRange(2_000_000, 2_999_999)
+
+	b := New[uint32](3, 4, 2_500_000)
+
+	fmt.Println(a.Count())
+	fmt.Println(iterator.Collect(And(a, b).Iterate()))
+	fmt.Println(a.Rank(2_000_000))
+	fmt.Println(a.Select(4))
+
+	// Output:
+	// 1000004
+	// [3 2500000]
+	// 5
+	// 2000000 true
+}
@@ -0,0 +1,330 @@
+// Package rope contains a rope, a sequence that supports inserting, deleting, splitting, and
+// concatenating anywhere in O(log n) time.
+package rope
+
+import (
+	"github.com/bradenaw/juniper/iterator"
+)
+
+// The most items kept in a single leaf. Larger leaves make the tree smaller and iteration faster,
+// but make each edit copy more.
+const maxLeaf = 128
+
+// Rope is a sequence of items, similar to a slice, that can be edited anywhere in O(log n) time
+// rather than needing to copy everything after the edit.
+//
+// Ropes are immutable, like strings: every operation returns a new Rope and leaves the original
+// unchanged. The new Rope shares most of its memory with the original, so keeping old versions
+// around, for example for undo, is cheap. Ropes are safe to use from multiple goroutines.
+//
+// The zero value is an empty Rope.
+type Rope[T any] struct {
+	root *node[T]
+}
+
+// node is a node of an AVL tree. Leaves hold the items, and a node's items are its left child's
+// followed by its right child's. The heights of a node's children differ by at most one.
+type node[T any] struct {
+	left   *node[T]
+	right  *node[T]
+	leaf   []T
+	length int
+	height int
+}
+
+func (n *node[T]) isLeaf() bool {
+	return n.left == nil
+}
+
+func height[T any](n *node[T]) int {
+	if n == nil {
+		return -1
+	}
+	return n.height
+}
+
+// newLeaf returns a leaf holding items, which must not be modified afterwards.
+func newLeaf[T any](items []T) *node[T] {
+	if len(items) == 0 {
+		return nil
+	}
+	return &node[T]{leaf: items, length: len(items)}
+}
+
+func newBranch[T any](left *node[T], right *node[T]) *node[T] {
+	h := left.height
+	if right.height > h {
+		h = right.height
+	}
+	return &node[T]{left: left, right: right, length: left.length + right.length, height: h + 1}
+}
+
+// New returns a Rope containing items. items is copied, so it may be modified afterwards.
+func New[T any](items ...T) Rope[T] {
+	return Rope[T]{root: build(items)}
+}
+
+// build returns a balanced tree holding a copy of items.
+func build[T any](items []T) *node[T] {
+	if len(items) <= maxLeaf {
+		return newLeaf(append([]T(nil), items...))
+	}
+	// Split on a multiple of maxLeaf so that the leaves are as full as possible.
+	nLeaves := (len(items) + maxLeaf - 1) / maxLeaf
+	mid := (nLeaves / 2) * maxLeaf
+	return newBranch(build(items[:mid]), build(items[mid:]))
+}
+
+// FromString returns a Rope containing the bytes of s.
+func FromString(s string) Rope[byte] {
+	return New([]byte(s)...)
+}
+
+// ToString returns the bytes of r as a string.
+func ToString(r Rope[byte]) string {
+	return string(r.AppendTo(make([]byte, 0, r.Len())))
+}
+
+// Len returns the number of items in r.
+func (r Rope[T]) Len() int {
+	if r.root == nil {
+		return 0
+	}
+	return r.root.length
+}
+
+// At returns the i-th item of r. It panics if i is out of range.
+func (r Rope[T]) At(i int) T {
+	if i < 0 || i >= r.Len() {
+		panic("rope index out of range")
+	}
+	n := r.root
+	for !n.isLeaf() {
+		if i < n.left.length {
+			n = n.left
+		} else {
+			i -= n.left.length
+			n = n.right
+		}
+	}
+	return n.leaf[i]
+}
+
+// Insert returns a Rope with items inserted before the i-th item of r, or at the end if i ==
+// r.Len(). It panics if i is out of range.
+func (r Rope[T]) Insert(i int, items ...T) Rope[T] {
+	checkIndex(i, r.Len())
+	if len(items) == 0 {
+		return r
+	}
+	// Small insertions, like typing, go into the existing leaf if they fit, so that they don't
+	// leave behind lots of tiny leaves.
+	if root, ok := insertInLeaf(r.root, i, items); ok {
+		return Rope[T]{root: root}
+	}
+	left, right := split(r.root, i)
+	return Rope[T]{root: join(join(left, build(items)), right)}
+}
+
+// insertInLeaf returns n with items inserted at i if the leaf they would go in has room for them,
+// and false otherwise.
+func insertInLeaf[T any](n *node[T], i int, items []T) (*node[T], bool) {
+	if n == nil {
+		return nil, false
+	}
+	if n.isLeaf() {
+		if n.length+len(items) > maxLeaf {
+			return nil, false
+		}
+		leaf := make([]T, 0, n.length+len(items))
+		leaf = append(leaf, n.leaf[:i]...)
+		leaf = append(leaf, items...)
+		leaf = append(leaf, n.leaf[i:]...)
+		return newLeaf(leaf), true
+	}
+	if i <= n.left.length {
+		left, ok := insertInLeaf(n.left, i, items)
+		if !ok {
+			return nil, false
+		}
+		return newBranch(left, n.right), true
+	}
+	right, ok := insertInLeaf(n.right, i-n.left.length, items)
+	if !ok {
+		return nil, false
+	}
+	return newBranch(n.left, right), true
+}
+
+// Delete returns a Rope without the items of r in [i, j). It panics if the range is out of
+// bounds.
+func (r Rope[T]) Delete(i int, j int) Rope[T] {
+	checkRange(i, j, r.Len())
+	left, rest := split(r.root, i)
+	_, right := split(rest, j-i)
+	return Rope[T]{root: join(left, right)}
+}
+
+// Slice returns a Rope with the items of r in [i, j). It panics if the range is out of bounds.
+func (r Rope[T]) Slice(i int, j int) Rope[T] {
+	checkRange(i, j, r.Len())
+	_, rest := split(r.root, i)
+	middle, _ := split(rest, j-i)
+	return Rope[T]{root: middle}
+}
+
+// Split returns a Rope with the first i items of r and a Rope with the rest. It panics if i is out
+// of range.
+func (r Rope[T]) Split(i int) (Rope[T], Rope[T]) {
+	checkIndex(i, r.Len())
+	left, right := split(r.root, i)
+	return Rope[T]{root: left}, Rope[T]{root: right}
+}
+
+// Concat returns a Rope with the items of each of ropes in order.
+func Concat[T any](ropes ...Rope[T]) Rope[T] {
+	var root *node[T]
+	for _, r := range ropes {
+		root = join(root, r.root)
+	}
+	return Rope[T]{root: root}
+}
+
+// AppendTo appends the items of r to dst and returns the resulting slice.
+func (r Rope[T]) AppendTo(dst []T) []T {
+	var visit func(n *node[T])
+	visit = func(n *node[T]) {
+		if n == nil {
+			return
+		}
+		if n.isLeaf() {
+			dst = append(dst, n.leaf...)
+			return
+		}
+		visit(n.left)
+		visit(n.right)
+	}
+	visit(r.root)
+	return dst
+}
+
+// Iterate returns an iterator over the items of r in [i, j). It panics if the range is out of
+// bounds.
+//
+// Since r cannot change, the iterator remains valid regardless of later edits.
+func (r Rope[T]) Iterate(i int, j int) iterator.Iterator[T] {
+	iter := &ropeIterator[T]{}
+	if root := r.Slice(i, j).root; root != nil {
+		iter.stack = append(iter.stack, root)
+	}
+	return iter
+}
+
+type ropeIterator[T any] struct {
+	// Nodes not yet visited, with the next on top.
+	stack []*node[T]
+	// The remaining items of the current leaf.
+	leaf []T
+}
+
+func (iter *ropeIterator[T]) Next() (T, bool) {
+	for len(iter.leaf) == 0 {
+		if len(iter.stack) == 0 {
+			var zero T
+			return zero, false
+		}
+		n := iter.stack[len(iter.stack)-1]
+		iter.stack = iter.stack[:len(iter.stack)-1]
+		for !n.isLeaf() {
+			iter.stack = append(iter.stack, n.right)
+			n = n.left
+		}
+		iter.leaf = n.leaf
+	}
+	item := iter.leaf[0]
+	iter.leaf = iter.leaf[1:]
+	return item, true
+}
+
+// join returns a tree with the items of left followed by the items of right, in O(|height(left) -
+// height(right)|) time.
+func join[T any](left *node[T], right *node[T]) *node[T] {
+	if left == nil {
+		return right
+	} else if right == nil {
+		return left
+	}
+	if left.isLeaf() && right.isLeaf() && left.length+right.length <= maxLeaf {
+		items := make([]T, 0, left.length+right.length)
+		items = append(items, left.leaf...)
+		items = append(items, right.leaf...)
+		return newLeaf(items)
+	}
+	if left.height > right.height+1 {
+		return balance(newBranch(left.left, join(left.right, right)))
+	} else if right.height > left.height+1 {
+		return balance(newBranch(join(left, right.left), right.right))
+	}
+	return newBranch(left, right)
+}
+
+// split returns a tree with the first i items of n and a tree with the rest.
+func split[T any](n *node[T], i int) (*node[T], *node[T]) {
+	if n == nil {
+		return nil, nil
+	} else if i <= 0 {
+		return nil, n
+	} else if i >= n.length {
+		return n, nil
+	}
+	if n.isLeaf() {
+		// The halves can share the original's memory since leaves are never modified.
+		return newLeaf(n.leaf[:i:i]), newLeaf(n.leaf[i:])
+	}
+	if i < n.left.length {
+		left, rest := split(n.left, i)
+		return left, join(rest, n.right)
+	}
+	rest, right := split(n.right, i-n.left.length)
+	return join(n.left, rest), right
+}
+
+// balance returns a tree with the same items as n whose children's heights differ by at most one,
+// given that n's children differ by at most two and are themselves balanced.
+func balance[T any](n *node[T]) *node[T] {
+	diff := height(n.left) - height(n.right)
+	if diff > 1 {
+		left := n.left
+		if height(left.left) < height(left.right) {
+			left = rotateLeft(left)
+		}
+		return rotateRight(newBranch(left, n.right))
+	} else if diff < -1 {
+		right := n.right
+		if height(right.right) < height(right.left) {
+			right = rotateRight(right)
+		}
+		return rotateLeft(newBranch(n.left, right))
+	}
+	return n
+}
+
+func rotateRight[T any](n *node[T]) *node[T] {
+	return newBranch(n.left.left, newBranch(n.left.right, n.right))
+}
+
+func rotateLeft[T any](n *node[T]) *node[T] {
+	return newBranch(newBranch(n.left, n.right.left), n.right.right)
+}
+
+func checkIndex(i int, n int) {
+	if i < 0 || i > n {
+		panic("rope index out of range")
+	}
+}
+
+func checkRange(i int, j int, n int) {
+	if i < 0 || j > n || i > j {
+		panic("rope range out of bounds")
+	}
+}
@@ -0,0 +1,198 @@
+package rope
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/bradenaw/juniper/internal/fuzz"
+	"github.com/bradenaw/juniper/internal/require2"
+	"github.com/bradenaw/juniper/iterator"
+	"github.com/bradenaw/juniper/xmath"
+	"github.com/bradenaw/juniper/xslices"
+)
+
+func checkInvariants[T any](t *testing.T, r Rope[T]) {
+	var visit func(n *node[T]) int
+	visit = func(n *node[T]) int {
+		if n.isLeaf() {
+			require2.Greater(t, len(n.leaf), 0)
+			require2.LessOrEqual(t, len(n.leaf), maxLeaf)
+			require2.Equal(t, len(n.leaf), n.length)
+			require2.Equal(t, 0, n.height)
+			return n.length
+		}
+		require2.True(t, n.right != nil)
+		diff := n.left.height - n.right.height
+		require2.True(t, diff >= -1 && diff <= 1)
+		require2.Equal(t, 1+xmath.Max(n.left.height, n.right.height), n.height)
+		length := visit(n.left) + visit(n.right)
+		require2.Equal(t, length, n.length)
+		return length
+	}
+	if r.root != nil {
+		visit(r.root)
+	}
+}
+
+func checkEqual(t *testing.T, expected []int, r Rope[int]) {
+	checkInvariants(t, r)
+	require2.Equal(t, len(expected), r.Len())
+	require2.SlicesEqual(t, expected, r.AppendTo(nil))
+}
+
+// items returns n distinct-ish items starting from start.
+func items(start int, n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = start + i
+	}
+	return out
+}
+
+func FuzzRope(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var r Rope[int]
+		var oracle []int
+		next := 0
+		// Keep some old versions around to check that they don't change.
+		type version struct {
+			r     Rope[int]
+			items []int
+		}
+		var versions []version
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				checkEqual(t, oracle, r)
+			},
+			func(at uint16, n byte) {
+				i := int(at) % (len(oracle) + 1)
+				added := items(next, int(n)*3)
+				next += len(added)
+				t.Logf("Insert(%d, %d items)", i, len(added))
+				r = r.Insert(i, added...)
+				oracle = xslices.Insert(oracle, i, added...)
+			},
+			func(from uint16, n uint16) {
+				if len(oracle) == 0 {
+					return
+				}
+				i := int(from) % len(oracle)
+				j := i + int(n)%(len(oracle)-i+1)
+				t.Logf("Delete(%d, %d)", i, j)
+				r = r.Delete(i, j)
+				oracle = xslices.Remove(oracle, i, j-i)
+			},
+			func(from uint16, n uint16) {
+				i := int(from) % (len(oracle) + 1)
+				j := i + int(n)%(len(oracle)-i+1)
+				checkEqual(t, oracle[i:j], r.Slice(i, j))
+				require2.SlicesEqual(t, oracle[i:j], iterator.Collect(r.Iterate(i, j)))
+			},
+			func(at uint16) {
+				i := int(at) % (len(oracle) + 1)
+				left, right := r.Split(i)
+				checkEqual(t, oracle[:i], left)
+				checkEqual(t, oracle[i:], right)
+				t.Logf("Concat(Split(%d))", i)
+				r = Concat(left, right)
+			},
+			func(at uint16) {
+				if len(oracle) == 0 {
+					return
+				}
+				i := int(at) % len(oracle)
+				require2.Equal(t, oracle[i], r.At(i))
+			},
+			func() {
+				// Doubling repeatedly would quickly run out of memory.
+				if len(oracle) > 4096 {
+					return
+				}
+				t.Logf("Concat(r, r)")
+				r = Concat(r, r)
+				oracle = append(xslices.Clone(oracle), oracle...)
+			},
+			func() {
+				versions = append(versions, version{r, xslices.Clone(oracle)})
+			},
+		)
+
+		for _, v := range versions {
+			checkEqual(t, v.items, v.r)
+		}
+	})
+}
+
+func TestRopeLarge(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	r := New(items(0, 10000)...)
+	oracle := items(0, 10000)
+	checkEqual(t, oracle, r)
+	next := len(oracle)
+
+	for k := 0; k < 2000; k++ {
+		switch rng.Intn(3) {
+		case 0:
+			i := rng.Intn(len(oracle) + 1)
+			added := items(next, rng.Intn(10))
+			next += len(added)
+			r = r.Insert(i, added...)
+			oracle = xslices.Insert(oracle, i, added...)
+		case 1:
+			i := rng.Intn(len(oracle) + 1)
+			j := i + rng.Intn(xmath.Max(1, (len(oracle)-i)/100+1))
+			if j > len(oracle) {
+				j = len(oracle)
+			}
+			r = r.Delete(i, j)
+			oracle = xslices.Remove(oracle, i, j-i)
+		case 2:
+			i := rng.Intn(len(oracle) + 1)
+			left, right := r.Split(i)
+			r = Concat(right, left)
+			oracle = append(xslices.Clone(oracle[i:]), oracle[:i]...)
+		}
+	}
+	checkEqual(t, oracle, r)
+}
+
+func ExampleRope() {
+	r := FromString("Hello world!")
+	r = r.Insert(5, []byte(",")...)
+	r = r.Delete(12, 13)
+	r = Concat(r, FromString(", and goodbye."))
+	fmt.Println(ToString(r))
+
+	greeting, rest := r.Split(6)
+	fmt.Println(ToString(greeting))
+	fmt.Println(ToString(rest.Slice(1, 6)))
+
+	// Output:
+	// Hello, world, and goodbye.
+	// Hello,
+	// world
+}
+
+func TestRopeTyping(t *testing.T) {
+	var r Rope[byte]
+	for i := 0; i < 1000; i++ {
+		r = r.Insert(r.Len()/2, 'a')
+	}
+	checkInvariants(t, r)
+	// Typing one byte at a time should still fill leaves reasonably.
+	leaves := 0
+	var visit func(n *node[byte])
+	visit = func(n *node[byte]) {
+		if n.isLeaf() {
+			leaves++
+			return
+		}
+		visit(n.left)
+		visit(n.right)
+	}
+	visit(r.root)
+	require2.LessOrEqual(t, leaves, 1000/(maxLeaf/2)+1)
+}
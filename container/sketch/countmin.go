@@ -0,0 +1,267 @@
+package sketch
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/bradenaw/juniper/container/xheap"
+	"github.com/bradenaw/juniper/internal/hash"
+	"github.com/bradenaw/juniper/iterator"
+	"github.com/bradenaw/juniper/stream"
+	"github.com/bradenaw/juniper/xsort"
+)
+
+// CountMin estimates how many times each item has been added to it using a count-min sketch
+// (http://dimacs.rutgers.edu/~graham/pubs/papers/cm-full.pdf), using a fixed amount of memory no
+// matter how many distinct items are added.
+//
+// Estimates are never lower than the true count, but may be higher because of collisions with other
+// items.
+//
+// CountMin is not safe for concurrent use.
+type CountMin[T any] struct {
+	hash  func(T) uint64
+	width uint64
+	depth int
+	// depth rows of width counters each, each row stored contiguously.
+	counters []uint64
+	total    uint64
+}
+
+// NewCountMin returns an empty CountMin. With probability 1-delta, the estimate for each item
+// exceeds its true count by at most epsilon times the total count of all items.
+//
+// CountMin uses about 8*e/epsilon*ln(1/delta) bytes of memory.
+func NewCountMin[T any](epsilon float64, delta float64, hash func(T) uint64) *CountMin[T] {
+	if epsilon <= 0 || delta <= 0 || delta >= 1 {
+		panic("CountMin requires epsilon > 0 and 0 < delta < 1")
+	}
+	return NewCountMinSize(
+		int(math.Ceil(math.E/epsilon)),
+		int(math.Ceil(math.Log(1/delta))),
+		hash,
+	)
+}
+
+// NewCountMinSize returns an empty CountMin with depth rows of width counters each. This is the
+// same as NewCountMin with epsilon e/width and delta e^-depth, and is useful when the sketch's size
+// should follow from something else, like the capacity of a cache.
+func NewCountMinSize[T any](width int, depth int, hash func(T) uint64) *CountMin[T] {
+	if width < 1 {
+		width = 1
+	}
+	if depth < 1 {
+		depth = 1
+	}
+	return &CountMin[T]{
+		hash:     hash,
+		width:    uint64(width),
+		depth:    depth,
+		counters: make([]uint64, depth*width),
+	}
+}
+
+// index returns the index into c.counters of item's counter in each row, given the hash of the
+// item.
+func (c *CountMin[T]) index(h uint64, row int) uint64 {
+	// Combine two hashes to choose the column in each row as in
+	// https://www.eecs.harvard.edu/~michaelm/postscripts/rsa2008.pdf.
+	h2 := hash.Mix(h) | 1
+	return uint64(row)*c.width + (h+uint64(row)*h2)%c.width
+}
+
+// Add adds count occurrences of item to the sketch.
+func (c *CountMin[T]) Add(item T, count uint64) {
+	h := c.hash(item)
+	for row := 0; row < c.depth; row++ {
+		c.counters[c.index(h, row)] += count
+	}
+	c.total += count
+}
+
+// AddAll adds one occurrence of each item from iter to the sketch.
+func (c *CountMin[T]) AddAll(iter iterator.Iterator[T]) {
+	for {
+		item, ok := iter.Next()
+		if !ok {
+			break
+		}
+		c.Add(item, 1)
+	}
+}
+
+// AddStream adds one occurrence of each item from s to the sketch, then closes s.
+func (c *CountMin[T]) AddStream(ctx context.Context, s stream.Stream[T]) error {
+	return consume(ctx, s, func(item T) { c.Add(item, 1) })
+}
+
+// Estimate returns an estimate of how many times item has been added.
+func (c *CountMin[T]) Estimate(item T) uint64 {
+	h := c.hash(item)
+	min := uint64(math.MaxUint64)
+	for row := 0; row < c.depth; row++ {
+		x := c.counters[c.index(h, row)]
+		if x < min {
+			min = x
+		}
+	}
+	return min
+}
+
+// Total returns the total count of all items that have been added.
+func (c *CountMin[T]) Total() uint64 {
+	return c.total
+}
+
+// Merge adds all of the counts in other to c.
+//
+// c and other must have been created with the same epsilon and delta, otherwise Merge returns an
+// error.
+func (c *CountMin[T]) Merge(other *CountMin[T]) error {
+	if c.width != other.width || c.depth != other.depth {
+		return errors.New("CountMins have different sizes")
+	}
+	for i, x := range other.counters {
+		c.counters[i] += x
+	}
+	c.total += other.total
+	return nil
+}
+
+// Halve divides every count by two, rounding down. Calling Halve periodically makes the sketch
+// favor recent occurrences over old ones, so that it follows a workload that changes over time.
+func (c *CountMin[T]) Halve() {
+	for i := range c.counters {
+		c.counters[i] /= 2
+	}
+	c.total /= 2
+}
+
+// Reset removes all items from the sketch.
+func (c *CountMin[T]) Reset() {
+	for i := range c.counters {
+		c.counters[i] = 0
+	}
+	c.total = 0
+}
+
+// ItemCount is an item and an estimate of how many times it has occurred.
+type ItemCount[T any] struct {
+	Item  T
+	Count uint64
+}
+
+// HeavyHitters tracks the items that occur most often in a stream, using a CountMin to estimate
+// each item's count.
+//
+// Memory use is that of the CountMin plus k items.
+//
+// HeavyHitters is not safe for concurrent use.
+type HeavyHitters[T comparable] struct {
+	k      int
+	counts *CountMin[T]
+	// The current top items, with the least frequent at the top of the heap.
+	top xheap.PriorityQueue[T, uint64]
+}
+
+// NewHeavyHitters returns an empty HeavyHitters that tracks the k most frequent items, using a
+// CountMin created with the given epsilon, delta, and hash.
+func NewHeavyHitters[T comparable](
+	k int,
+	epsilon float64,
+	delta float64,
+	hash func(T) uint64,
+) *HeavyHitters[T] {
+	return &HeavyHitters[T]{
+		k:      k,
+		counts: NewCountMin(epsilon, delta, hash),
+		top:    xheap.NewPriorityQueue[T](xsort.OrderedLess[uint64], nil),
+	}
+}
+
+// Add adds count occurrences of item.
+func (h *HeavyHitters[T]) Add(item T, count uint64) {
+	h.counts.Add(item, count)
+	h.offer(item, h.counts.Estimate(item))
+}
+
+// offer considers item for the top k with the given estimated count.
+func (h *HeavyHitters[T]) offer(item T, estimate uint64) {
+	if h.top.Contains(item) || h.top.Len() < h.k {
+		h.top.Update(item, estimate)
+		return
+	}
+	if h.k > 0 && estimate > h.top.Priority(h.top.Peek()) {
+		h.top.Pop()
+		h.top.Update(item, estimate)
+	}
+}
+
+// AddAll adds one occurrence of each item from iter.
+func (h *HeavyHitters[T]) AddAll(iter iterator.Iterator[T]) {
+	for {
+		item, ok := iter.Next()
+		if !ok {
+			break
+		}
+		h.Add(item, 1)
+	}
+}
+
+// AddStream adds one occurrence of each item from s, then closes s.
+func (h *HeavyHitters[T]) AddStream(ctx context.Context, s stream.Stream[T]) error {
+	return consume(ctx, s, func(item T) { h.Add(item, 1) })
+}
+
+// Estimate returns an estimate of how many times item has been added.
+func (h *HeavyHitters[T]) Estimate(item T) uint64 {
+	return h.counts.Estimate(item)
+}
+
+// Top returns the k most frequent items seen so far and their estimated counts, most frequent
+// first.
+//
+// Since counts are estimates, an item that occurs about as often as the k-th most frequent item
+// may be included in place of it.
+func (h *HeavyHitters[T]) Top() []ItemCount[T] {
+	out := make([]ItemCount[T], 0, h.top.Len())
+	iter := h.top.Iterate()
+	for {
+		item, ok := iter.Next()
+		if !ok {
+			break
+		}
+		out = append(out, ItemCount[T]{Item: item, Count: h.top.Priority(item)})
+	}
+	xsort.Slice(out, func(a, b ItemCount[T]) bool { return a.Count > b.Count })
+	return out
+}
+
+// Merge adds all of the counts in other to h.
+//
+// Afterwards, Top only includes items that were in the top k of either h or other, so an item that
+// was just outside of the top k of both may be missing even if its combined count would place it
+// in the top k.
+//
+// h and other must have been created with the same arguments to NewHeavyHitters, otherwise Merge
+// returns an error.
+func (h *HeavyHitters[T]) Merge(other *HeavyHitters[T]) error {
+	if h.k != other.k {
+		return errors.New("HeavyHitters track different numbers of items")
+	}
+	err := h.counts.Merge(other.counts)
+	if err != nil {
+		return err
+	}
+	// Only items that were in the top k of either are considered, so an item that was just outside
+	// the top k of both may be missed.
+	candidates := make([]T, 0, h.top.Len()+other.top.Len())
+	candidates = append(candidates, iterator.Collect(h.top.Iterate())...)
+	candidates = append(candidates, iterator.Collect(other.top.Iterate())...)
+	h.top = xheap.NewPriorityQueue[T](xsort.OrderedLess[uint64], nil)
+	for _, item := range candidates {
+		h.offer(item, h.counts.Estimate(item))
+	}
+	return nil
+}
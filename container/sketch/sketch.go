@@ -0,0 +1,30 @@
+// Package sketch contains probabilistic summaries of streams of items that are too large to hold in
+// memory, such as the number of distinct items and how often each item occurs.
+//
+// Sketches take a function that hashes items to uint64. The hash should be of good quality, since
+// every bit of it is used. filter.HashString, filter.HashBytes, and filter.HashInt can be used for
+// common item types.
+//
+// Sketches of the same size can be merged, so that items can be added to separate sketches in
+// parallel and the results combined afterwards.
+package sketch
+
+import (
+	"context"
+
+	"github.com/bradenaw/juniper/stream"
+)
+
+// consume calls f for every item in s, then closes s.
+func consume[T any](ctx context.Context, s stream.Stream[T], f func(T)) error {
+	defer s.Close()
+	for {
+		item, err := s.Next(ctx)
+		if err == stream.End {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		f(item)
+	}
+}
@@ -0,0 +1,132 @@
+package sketch
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bradenaw/juniper/container/filter"
+	"github.com/bradenaw/juniper/internal/require2"
+	"github.com/bradenaw/juniper/iterator"
+	"github.com/bradenaw/juniper/stream"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	for _, n := range []int{0, 10, 1000, 100000, 1000000} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			h := NewHyperLogLog(14, filter.HashInt[int])
+			for i := 0; i < n; i++ {
+				// Every item twice, to make sure duplicates aren't counted.
+				h.Add(i)
+				h.Add(i)
+			}
+			estimate := h.Count()
+			t.Logf("estimated %d for %d", estimate, n)
+			// Standard error at precision 14 is about 0.8%, so this is about 4 standard errors.
+			require2.InDelta(t, float64(estimate), float64(n), 0.03*float64(n)+1)
+		})
+	}
+}
+
+func TestHyperLogLogMerge(t *testing.T) {
+	ctx := context.Background()
+	a := NewHyperLogLog(12, filter.HashInt[int])
+	b := NewHyperLogLog(12, filter.HashInt[int])
+	a.AddAll(iterator.Counter(60000))
+	// Overlaps with a for 20000 items.
+	require2.NoError(t, b.AddStream(ctx, stream.FromIterator(
+		iterator.Map(iterator.Counter(60000), func(i int) int { return i + 40000 }),
+	)))
+
+	require2.NoError(t, a.Merge(b))
+	estimate := a.Count()
+	t.Logf("estimated %d for %d", estimate, 100000)
+	require2.InDelta(t, float64(estimate), 100000, 5000)
+
+	require2.Error(t, a.Merge(NewHyperLogLog(10, filter.HashInt[int])))
+}
+
+func TestCountMin(t *testing.T) {
+	const epsilon = 0.001
+	c := NewCountMin(epsilon, 0.01, filter.HashInt[int])
+	r := rand.New(rand.NewSource(0))
+	zipf := rand.NewZipf(r, 1.2, 1, 10000)
+	const n = 100000
+	actual := make(map[int]uint64)
+	for i := 0; i < n; i++ {
+		x := int(zipf.Uint64())
+		c.Add(x, 1)
+		actual[x]++
+	}
+	require2.Equal(t, uint64(n), c.Total())
+
+	tooHigh := 0
+	for x, count := range actual {
+		estimate := c.Estimate(x)
+		require2.GreaterOrEqual(t, estimate, count)
+		if float64(estimate-count) > epsilon*n {
+			tooHigh++
+		}
+	}
+	// Each is allowed to exceed the bound with probability delta.
+	require2.LessOrEqual(t, float64(tooHigh), 0.01*float64(len(actual)))
+
+	other := NewCountMin(epsilon, 0.01, filter.HashInt[int])
+	other.Add(-1, 1000)
+	require2.NoError(t, c.Merge(other))
+	require2.GreaterOrEqual(t, c.Estimate(-1), uint64(1000))
+	require2.Equal(t, uint64(n+1000), c.Total())
+
+	require2.Error(t, c.Merge(NewCountMin(0.01, 0.01, filter.HashInt[int])))
+}
+
+func TestCountMinHalve(t *testing.T) {
+	c := NewCountMinSize(64, 4, filter.HashInt[int])
+	c.Add(1, 10)
+	c.Add(2, 3)
+	c.Halve()
+	require2.Equal(t, uint64(5), c.Estimate(1))
+	require2.Equal(t, uint64(1), c.Estimate(2))
+	require2.Equal(t, uint64(6), c.Total())
+}
+
+func TestHeavyHitters(t *testing.T) {
+	const k = 5
+	r := rand.New(rand.NewSource(0))
+	zipf := rand.NewZipf(r, 1.5, 1, 100000)
+
+	// Split across two, as if done in parallel.
+	hs := [2]*HeavyHitters[uint64]{
+		NewHeavyHitters[uint64](k, 0.0001, 0.01, filter.HashInt[uint64]),
+		NewHeavyHitters[uint64](k, 0.0001, 0.01, filter.HashInt[uint64]),
+	}
+	actual := make(map[uint64]uint64)
+	for i := 0; i < 200000; i++ {
+		x := zipf.Uint64()
+		hs[i%2].Add(x, 1)
+		actual[x]++
+	}
+	require2.NoError(t, hs[0].Merge(hs[1]))
+
+	top := hs[0].Top()
+	require2.Equal(t, k, len(top))
+	for i, ic := range top {
+		// With this skew, the most frequent items are 0, 1, 2, ... in order.
+		require2.Equal(t, uint64(i), ic.Item)
+		require2.GreaterOrEqual(t, ic.Count, actual[ic.Item])
+		require2.LessOrEqual(t, ic.Count, actual[ic.Item]+20)
+	}
+}
+
+func ExampleHyperLogLog() {
+	h := NewHyperLogLog(14, filter.HashString)
+	for i := 0; i < 100000; i++ {
+		h.Add(fmt.Sprintf("user-%d", i%5000))
+	}
+	fmt.Println(math.Round(float64(h.Count())/100) * 100)
+
+	// Output:
+	// 5000
+}
@@ -0,0 +1,87 @@
+// Package spatial contains an R-tree for finding items by their location in two-dimensional space.
+package spatial
+
+import (
+	"fmt"
+	"math"
+)
+
+// Point is a location in two-dimensional space.
+type Point struct {
+	X float64
+	Y float64
+}
+
+// String returns p formatted as "(x, y)".
+func (p Point) String() string {
+	return fmt.Sprintf("(%v, %v)", p.X, p.Y)
+}
+
+// Rect is an axis-aligned rectangle, including its edges. A Rect with Min == Max is a single point.
+type Rect struct {
+	// The corner with the smallest X and Y.
+	Min Point
+	// The corner with the largest X and Y.
+	Max Point
+}
+
+// PointRect returns the Rect that contains only p.
+func PointRect(p Point) Rect {
+	return Rect{Min: p, Max: p}
+}
+
+// String returns r formatted as "[(minX, minY), (maxX, maxY)]".
+func (r Rect) String() string {
+	return fmt.Sprintf("[%s, %s]", r.Min, r.Max)
+}
+
+// Intersects returns true if r and other have any point in common, including if they only touch at
+// an edge.
+func (r Rect) Intersects(other Rect) bool {
+	return r.Min.X <= other.Max.X && other.Min.X <= r.Max.X &&
+		r.Min.Y <= other.Max.Y && other.Min.Y <= r.Max.Y
+}
+
+// Contains returns true if other is entirely inside r.
+func (r Rect) Contains(other Rect) bool {
+	return r.Min.X <= other.Min.X && other.Max.X <= r.Max.X &&
+		r.Min.Y <= other.Min.Y && other.Max.Y <= r.Max.Y
+}
+
+// Area returns the area of r.
+func (r Rect) Area() float64 {
+	return (r.Max.X - r.Min.X) * (r.Max.Y - r.Min.Y)
+}
+
+// Distance returns the Euclidean distance from p to the closest point in r, which is zero if r
+// contains p.
+func (r Rect) Distance(p Point) float64 {
+	return math.Sqrt(r.distanceSquared(p))
+}
+
+func (r Rect) distanceSquared(p Point) float64 {
+	dx := axisDistance(p.X, r.Min.X, r.Max.X)
+	dy := axisDistance(p.Y, r.Min.Y, r.Max.Y)
+	return dx*dx + dy*dy
+}
+
+func axisDistance(x, lo, hi float64) float64 {
+	if x < lo {
+		return lo - x
+	} else if x > hi {
+		return x - hi
+	}
+	return 0
+}
+
+// union returns the smallest Rect that contains both r and other.
+func (r Rect) union(other Rect) Rect {
+	return Rect{
+		Min: Point{math.Min(r.Min.X, other.Min.X), math.Min(r.Min.Y, other.Min.Y)},
+		Max: Point{math.Max(r.Max.X, other.Max.X), math.Max(r.Max.Y, other.Max.Y)},
+	}
+}
+
+func (r Rect) center() Point {
+	return Point{(r.Min.X + r.Max.X) / 2, (r.Min.Y + r.Max.Y) / 2}
+}
@@ -0,0 +1,399 @@
+package spatial
+
+import (
+	"math"
+
+	"github.com/bradenaw/juniper/container/xheap"
+	"github.com/bradenaw/juniper/iterator"
+	"github.com/bradenaw/juniper/xmath"
+	"github.com/bradenaw/juniper/xsort"
+)
+
+const (
+	maxEntries = 16
+	minEntries = maxEntries * 2 / 5
+)
+
+// Entry is an item in an RTree and the bounding box it was added with.
+type Entry[T any] struct {
+	Box  Rect
+	Item T
+}
+
+// RTree is a spatial index of items with bounding boxes. It can find the items whose boxes
+// intersect a rectangle, or the items nearest to a point, without looking at most of the others.
+//
+// The same item can appear more than once with different boxes.
+//
+// The zero value is an empty RTree ready to use.
+type RTree[T comparable] struct {
+	root *node[T]
+	size int
+}
+
+// node is a node of the tree. Every leaf is at the same depth, and every node other than the root
+// has at most maxEntries entries.
+type node[T comparable] struct {
+	leaf    bool
+	entries []nodeEntry[T]
+}
+
+// nodeEntry is either an item, in a leaf, or a child node, in an internal node. box is the bounding
+// box of the item, or of every entry in child.
+type nodeEntry[T comparable] struct {
+	box   Rect
+	child *node[T]
+	item  T
+}
+
+func (n *node[T]) bounds() Rect {
+	box := n.entries[0].box
+	for _, e := range n.entries[1:] {
+		box = box.union(e.box)
+	}
+	return box
+}
+
+// BulkLoad returns an RTree containing entries. It is faster than inserting each entry separately,
+// and usually produces a tree that is faster to search.
+func BulkLoad[T comparable](entries []Entry[T]) *RTree[T] {
+	t := &RTree[T]{size: len(entries)}
+	if len(entries) == 0 {
+		return t
+	}
+	level := make([]nodeEntry[T], len(entries))
+	for i, e := range entries {
+		level[i] = nodeEntry[T]{box: e.Box, item: e.Item}
+	}
+	nodes := pack(level, true)
+	for len(nodes) > 1 {
+		level = level[:0]
+		for _, n := range nodes {
+			level = append(level, nodeEntry[T]{box: n.bounds(), child: n})
+		}
+		nodes = pack(level, false)
+	}
+	t.root = nodes[0]
+	return t
+}
+
+// pack groups entries into nodes using the Sort-Tile-Recursive algorithm: entries are sorted into
+// vertical slabs by X, and then each slab is sorted by Y and cut into nodes, so that each node
+// covers a small and roughly square area.
+func pack[T comparable](entries []nodeEntry[T], leaf bool) []*node[T] {
+	nNodes := (len(entries) + maxEntries - 1) / maxEntries
+	slabSize := int(math.Ceil(math.Sqrt(float64(nNodes)))) * maxEntries
+	xsort.Slice(entries, func(a, b nodeEntry[T]) bool { return a.box.center().X < b.box.center().X })
+
+	nodes := make([]*node[T], 0, nNodes)
+	for len(entries) > 0 {
+		slab := entries[:xmath.Min(slabSize, len(entries))]
+		entries = entries[len(slab):]
+		xsort.Slice(slab, func(a, b nodeEntry[T]) bool { return a.box.center().Y < b.box.center().Y })
+		for len(slab) > 0 {
+			n := xmath.Min(maxEntries, len(slab))
+			nodes = append(nodes, &node[T]{
+				leaf:    leaf,
+				entries: append([]nodeEntry[T](nil), slab[:n]...),
+			})
+			slab = slab[n:]
+		}
+	}
+	return nodes
+}
+
+// Len returns the number of entries in t.
+func (t *RTree[T]) Len() int {
+	return t.size
+}
+
+// Insert adds item with the bounding box box.
+func (t *RTree[T]) Insert(box Rect, item T) {
+	t.insertEntry(nodeEntry[T]{box: box, item: item})
+	t.size++
+}
+
+func (t *RTree[T]) insertEntry(e nodeEntry[T]) {
+	if t.root == nil {
+		t.root = &node[T]{leaf: true}
+	}
+	split := t.insert(t.root, e)
+	if split != nil {
+		oldRoot := t.root
+		t.root = &node[T]{
+			entries: []nodeEntry[T]{
+				{box: oldRoot.bounds(), child: oldRoot},
+				{box: split.bounds(), child: split},
+			},
+		}
+	}
+}
+
+// insert adds e to a leaf below n. If n ends up with too many entries, it is split in two and the
+// new sibling is returned.
+func (t *RTree[T]) insert(n *node[T], e nodeEntry[T]) *node[T] {
+	if n.leaf {
+		n.entries = append(n.entries, e)
+	} else {
+		i := chooseSubtree(n, e.box)
+		child := n.entries[i].child
+		split := t.insert(child, e)
+		n.entries[i].box = child.bounds()
+		if split != nil {
+			n.entries = append(n.entries, nodeEntry[T]{box: split.bounds(), child: split})
+		}
+	}
+	if len(n.entries) > maxEntries {
+		return splitNode(n)
+	}
+	return nil
+}
+
+// chooseSubtree returns the index of the entry of n whose box needs to grow the least to include
+// box, breaking ties by the smallest area.
+func chooseSubtree[T comparable](n *node[T], box Rect) int {
+	best := 0
+	bestGrowth := math.Inf(1)
+	bestArea := math.Inf(1)
+	for i, e := range n.entries {
+		area := e.box.Area()
+		growth := e.box.union(box).Area() - area
+		if growth < bestGrowth || (growth == bestGrowth && area < bestArea) {
+			best = i
+			bestGrowth = growth
+			bestArea = area
+		}
+	}
+	return best
+}
+
+// splitNode splits n's entries into two groups using Guttman's quadratic split, leaving the first
+// in n and returning a new node with the second.
+func splitNode[T comparable](n *node[T]) *node[T] {
+	entries := n.entries
+
+	// Start each group with the pair of entries that would waste the most area if they were
+	// together.
+	seedA, seedB := 0, 1
+	worst := math.Inf(-1)
+	for i := range entries {
+		for j := i + 1; j < len(entries); j++ {
+			waste := entries[i].box.union(entries[j].box).Area() -
+				entries[i].box.Area() - entries[j].box.Area()
+			if waste > worst {
+				seedA, seedB = i, j
+				worst = waste
+			}
+		}
+	}
+	a := []nodeEntry[T]{entries[seedA]}
+	b := []nodeEntry[T]{entries[seedB]}
+	boxA := entries[seedA].box
+	boxB := entries[seedB].box
+	remaining := make([]nodeEntry[T], 0, len(entries)-2)
+	for i, e := range entries {
+		if i != seedA && i != seedB {
+			remaining = append(remaining, e)
+		}
+	}
+
+	for len(remaining) > 0 {
+		// If one group needs all of the rest to have enough entries, give them to it.
+		if len(a)+len(remaining) <= minEntries {
+			a = append(a, remaining...)
+			break
+		}
+		if len(b)+len(remaining) <= minEntries {
+			b = append(b, remaining...)
+			break
+		}
+
+		// Otherwise, assign the entry with the strongest preference for one group over the other.
+		next := 0
+		var nextGrowthA, nextGrowthB float64
+		strongest := math.Inf(-1)
+		for i, e := range remaining {
+			growthA := boxA.union(e.box).Area() - boxA.Area()
+			growthB := boxB.union(e.box).Area() - boxB.Area()
+			if preference := math.Abs(growthA - growthB); preference > strongest {
+				next = i
+				nextGrowthA, nextGrowthB = growthA, growthB
+				strongest = preference
+			}
+		}
+		e := remaining[next]
+		remaining[next] = remaining[len(remaining)-1]
+		remaining = remaining[:len(remaining)-1]
+
+		toA := nextGrowthA < nextGrowthB
+		if nextGrowthA == nextGrowthB {
+			toA = boxA.Area() < boxB.Area() || (boxA.Area() == boxB.Area() && len(a) <= len(b))
+		}
+		if toA {
+			a = append(a, e)
+			boxA = boxA.union(e.box)
+		} else {
+			b = append(b, e)
+			boxB = boxB.union(e.box)
+		}
+	}
+
+	n.entries = a
+	return &node[T]{leaf: n.leaf, entries: b}
+}
+
+// Delete removes item with the bounding box box, and returns true if it was found. If the same item
+// was inserted more than once with box, only one of them is removed.
+func (t *RTree[T]) Delete(box Rect, item T) bool {
+	if t.root == nil {
+		return false
+	}
+	var orphans []nodeEntry[T]
+	if !t.delete(t.root, box, item, &orphans) {
+		return false
+	}
+	t.size--
+	for !t.root.leaf && len(t.root.entries) == 1 {
+		t.root = t.root.entries[0].child
+	}
+	if len(t.root.entries) == 0 {
+		t.root = nil
+	}
+	for _, e := range orphans {
+		t.insertEntry(e)
+	}
+	return true
+}
+
+// delete removes item from below n. Children that have too few entries left are removed, and the
+// items below them are added to orphans to be inserted again.
+func (t *RTree[T]) delete(n *node[T], box Rect, item T, orphans *[]nodeEntry[T]) bool {
+	if n.leaf {
+		for i, e := range n.entries {
+			if e.box == box && e.item == item {
+				n.entries = append(n.entries[:i], n.entries[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+	for i, e := range n.entries {
+		if !e.box.Contains(box) || !t.delete(e.child, box, item, orphans) {
+			continue
+		}
+		if len(e.child.entries) < minEntries {
+			n.entries = append(n.entries[:i], n.entries[i+1:]...)
+			collectItems(e.child, orphans)
+		} else {
+			n.entries[i].box = e.child.bounds()
+		}
+		return true
+	}
+	return false
+}
+
+func collectItems[T comparable](n *node[T], out *[]nodeEntry[T]) {
+	if n.leaf {
+		*out = append(*out, n.entries...)
+		return
+	}
+	for _, e := range n.entries {
+		collectItems(e.child, out)
+	}
+}
+
+// Search returns an iterator over the entries whose boxes intersect r, in no particular order.
+//
+// The iterator is invalidated if t is modified.
+func (t *RTree[T]) Search(r Rect) iterator.Iterator[Entry[T]] {
+	iter := &searchIterator[T]{r: r}
+	if t.root != nil {
+		iter.stack = append(iter.stack, t.root)
+	}
+	return iter
+}
+
+type searchIterator[T comparable] struct {
+	r     Rect
+	stack []*node[T]
+	// The entries of the current leaf that haven't been looked at yet.
+	leaf []nodeEntry[T]
+}
+
+func (iter *searchIterator[T]) Next() (Entry[T], bool) {
+	for {
+		for len(iter.leaf) > 0 {
+			e := iter.leaf[0]
+			iter.leaf = iter.leaf[1:]
+			if e.box.Intersects(iter.r) {
+				return Entry[T]{Box: e.box, Item: e.item}, true
+			}
+		}
+		if len(iter.stack) == 0 {
+			var zero Entry[T]
+			return zero, false
+		}
+		n := iter.stack[len(iter.stack)-1]
+		iter.stack = iter.stack[:len(iter.stack)-1]
+		if n.leaf {
+			iter.leaf = n.entries
+			continue
+		}
+		for _, e := range n.entries {
+			if e.box.Intersects(iter.r) {
+				iter.stack = append(iter.stack, e.child)
+			}
+		}
+	}
+}
+
+// Nearest returns an iterator over all of the entries in order of increasing distance from p,
+// measured to the closest point of each entry's box. Entries at the same distance are in no
+// particular order.
+//
+// The iterator is lazy, so the k nearest entries can be found with iterator.First(t.Nearest(p), k)
+// in about O(k * log(n)) time.
+//
+// The iterator is invalidated if t is modified.
+func (t *RTree[T]) Nearest(p Point) iterator.Iterator[Entry[T]] {
+	iter := &nearestIterator[T]{
+		p: p,
+		h: xheap.New(func(a, b nearestCandidate[T]) bool {
+			return a.distance < b.distance
+		}, nil),
+	}
+	if t.root != nil {
+		iter.h.Push(nearestCandidate[T]{
+			distance: t.root.bounds().distanceSquared(p),
+			entry:    nodeEntry[T]{child: t.root},
+		})
+	}
+	return iter
+}
+
+type nearestCandidate[T comparable] struct {
+	// Squared distance from p to entry.box.
+	distance float64
+	entry    nodeEntry[T]
+}
+
+type nearestIterator[T comparable] struct {
+	p Point
+	// Items and nodes that have been seen but not yet yielded or expanded, closest first. Because
+	// a node's box contains everything below it, nothing below a node can be closer than it.
+	h xheap.Heap[nearestCandidate[T]]
+}
+
+func (iter *nearestIterator[T]) Next() (Entry[T], bool) {
+	for iter.h.Len() > 0 {
+		c := iter.h.Pop()
+		if c.entry.child == nil {
+			return Entry[T]{Box: c.entry.box, Item: c.entry.item}, true
+		}
+		for _, e := range c.entry.child.entries {
+			iter.h.Push(nearestCandidate[T]{distance: e.box.distanceSquared(iter.p), entry: e})
+		}
+	}
+	var zero Entry[T]
+	return zero, false
+}
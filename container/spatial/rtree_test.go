@@ -0,0 +1,180 @@
+package spatial
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/bradenaw/juniper/internal/fuzz"
+	"github.com/bradenaw/juniper/internal/require2"
+	"github.com/bradenaw/juniper/iterator"
+	"github.com/bradenaw/juniper/xslices"
+)
+
+func rectFromBytes(x, y, w, h byte) Rect {
+	return Rect{
+		Min: Point{float64(x), float64(y)},
+		Max: Point{float64(x) + float64(w%16), float64(y) + float64(h%16)},
+	}
+}
+
+// checkInvariants checks that every leaf is at the same depth, that nodes aren't too full, and that
+// every box is exactly the bounds of what's below it.
+func checkInvariants[T comparable](t *testing.T, tree *RTree[T]) {
+	if tree.root == nil {
+		require2.Equal(t, 0, tree.Len())
+		return
+	}
+	leafDepth := -1
+	count := 0
+	var visit func(n *node[T], depth int)
+	visit = func(n *node[T], depth int) {
+		require2.Greater(t, len(n.entries), 0)
+		require2.LessOrEqual(t, len(n.entries), maxEntries)
+		if n.leaf {
+			if leafDepth == -1 {
+				leafDepth = depth
+			}
+			require2.Equal(t, leafDepth, depth)
+			count += len(n.entries)
+			return
+		}
+		for _, e := range n.entries {
+			require2.Equal(t, e.child.bounds(), e.box)
+			visit(e.child, depth+1)
+		}
+	}
+	visit(tree.root, 0)
+	require2.Equal(t, tree.Len(), count)
+}
+
+func FuzzRTree(f *testing.F) {
+	f.Fuzz(func(t *testing.T, bulk []byte, b []byte) {
+		var oracle []Entry[int]
+		for i := 0; i+4 <= len(bulk); i += 4 {
+			oracle = append(oracle, Entry[int]{
+				Box:  rectFromBytes(bulk[i], bulk[i+1], bulk[i+2], bulk[i+3]),
+				Item: i / 4,
+			})
+		}
+		tree := BulkLoad(xslices.Clone(oracle))
+		nextItem := len(oracle)
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				checkInvariants(t, tree)
+				require2.Equal(t, len(oracle), tree.Len())
+			},
+			func(x, y, w, h byte) {
+				box := rectFromBytes(x, y, w, h)
+				t.Logf("Insert(%s, %d)", box, nextItem)
+				tree.Insert(box, nextItem)
+				oracle = append(oracle, Entry[int]{Box: box, Item: nextItem})
+				nextItem++
+			},
+			func(i byte) {
+				if len(oracle) == 0 {
+					return
+				}
+				idx := int(i) % len(oracle)
+				e := oracle[idx]
+				t.Logf("Delete(%s, %d)", e.Box, e.Item)
+				require2.True(t, tree.Delete(e.Box, e.Item))
+				oracle = xslices.Remove(oracle, idx, 1)
+				require2.True(t, !tree.Delete(e.Box, e.Item))
+			},
+			func(x, y, w, h byte) {
+				r := rectFromBytes(x, y, w, h)
+				var expected []Entry[int]
+				for _, e := range oracle {
+					if e.Box.Intersects(r) {
+						expected = append(expected, e)
+					}
+				}
+				require2.ElementsMatch(t, expected, iterator.Collect(tree.Search(r)))
+			},
+			func(x, y byte) {
+				p := Point{float64(x), float64(y)}
+				nearest := iterator.Collect(tree.Nearest(p))
+				require2.ElementsMatch(t, oracle, nearest)
+				for i := 1; i < len(nearest); i++ {
+					require2.LessOrEqual(t, nearest[i-1].Box.Distance(p), nearest[i].Box.Distance(p))
+				}
+			},
+		)
+	})
+}
+
+func TestRTreeLarge(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	randomRect := func() Rect {
+		x := r.Float64() * 1000
+		y := r.Float64() * 1000
+		return Rect{Min: Point{x, y}, Max: Point{x + r.Float64()*10, y + r.Float64()*10}}
+	}
+
+	var entries []Entry[int]
+	for i := 0; i < 5000; i++ {
+		entries = append(entries, Entry[int]{Box: randomRect(), Item: i})
+	}
+	inserted := &RTree[int]{}
+	for _, e := range entries {
+		inserted.Insert(e.Box, e.Item)
+	}
+	bulk := BulkLoad(xslices.Clone(entries))
+
+	for _, tree := range []*RTree[int]{inserted, bulk} {
+		checkInvariants(t, tree)
+		for i := 0; i < 100; i++ {
+			query := randomRect()
+			var expected []Entry[int]
+			for _, e := range entries {
+				if e.Box.Intersects(query) {
+					expected = append(expected, e)
+				}
+			}
+			require2.ElementsMatch(t, expected, iterator.Collect(tree.Search(query)))
+
+			p := Point{r.Float64() * 1000, r.Float64() * 1000}
+			nearest := iterator.Collect(iterator.First(tree.Nearest(p), 10))
+			require2.Equal(t, 10, len(nearest))
+			// Nothing that wasn't returned is closer than the last one that was.
+			for _, e := range entries {
+				if xslices.Index(nearest, e) == -1 {
+					require2.GreaterOrEqual(t, e.Box.Distance(p), nearest[9].Box.Distance(p))
+				}
+			}
+		}
+		for _, e := range entries[:4000] {
+			require2.True(t, tree.Delete(e.Box, e.Item))
+		}
+		checkInvariants(t, tree)
+		require2.ElementsMatch(t, entries[4000:], iterator.Collect(tree.Nearest(Point{})))
+	}
+}
+
+func ExampleRTree() {
+	var tree RTree[string]
+	tree.Insert(PointRect(Point{1, 1}), "a")
+	tree.Insert(PointRect(Point{5, 2}), "b")
+	tree.Insert(Rect{Min: Point{3, 3}, Max: Point{4, 8}}, "c")
+	tree.Insert(PointRect(Point{9, 9}), "d")
+
+	search := tree.Search(Rect{Min: Point{0, 0}, Max: Point{5, 5}})
+	fmt.Println(len(iterator.Collect(search)))
+
+	nearest := iterator.First(tree.Nearest(Point{4, 9}), 2)
+	for {
+		e, ok := nearest.Next()
+		if !ok {
+			break
+		}
+		fmt.Println(e.Item, e.Box.Distance(Point{4, 9}))
+	}
+
+	// Output:
+	// 3
+	// c 1
+	// d 5
+}
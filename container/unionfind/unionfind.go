@@ -0,0 +1,203 @@
+// Package unionfind contains a disjoint-set data structure.
+package unionfind
+
+import (
+	"github.com/bradenaw/juniper/iterator"
+)
+
+// DisjointSet partitions items into disjoint sets, which can be merged. It is also known as a
+// union-find data structure (https://en.wikipedia.org/wiki/Disjoint-set_data_structure).
+//
+// Items that have not been added are treated as being in a set by themselves.
+//
+// Add, Union, Find, Connected, SetSize, and NumSets take amortized nearly O(1) time.
+//
+// DisjointSet is not safe for concurrent use, including concurrent calls to Find, which modifies
+// the structure to speed up later calls.
+type DisjointSet[T comparable] struct {
+	index map[T]int
+	items []T
+	// parent[i] is the index of the parent of items[i], or i if items[i] is the representative of
+	// its set.
+	parent []int
+	// For representatives, an upper bound on the height of the tree below them.
+	rank []uint8
+	// For representatives, the number of items in the set.
+	size []int
+	// The members of each set form a cycle through next, so that they can be listed without
+	// looking at every item.
+	next []int
+
+	numSets int
+}
+
+// New returns an empty DisjointSet.
+func New[T comparable]() *DisjointSet[T] {
+	return &DisjointSet[T]{index: make(map[T]int)}
+}
+
+// Len returns the number of items that have been added.
+func (d *DisjointSet[T]) Len() int {
+	return len(d.items)
+}
+
+// NumSets returns the number of disjoint sets among the items that have been added.
+func (d *DisjointSet[T]) NumSets() int {
+	return d.numSets
+}
+
+// Add adds x in a set by itself, if it has not already been added. Returns true if x was added.
+func (d *DisjointSet[T]) Add(x T) bool {
+	_, ok := d.index[x]
+	if ok {
+		return false
+	}
+	d.add(x)
+	return true
+}
+
+func (d *DisjointSet[T]) add(x T) int {
+	i := len(d.items)
+	d.index[x] = i
+	d.items = append(d.items, x)
+	d.parent = append(d.parent, i)
+	d.rank = append(d.rank, 0)
+	d.size = append(d.size, 1)
+	d.next = append(d.next, i)
+	d.numSets++
+	return i
+}
+
+// Union merges the sets containing a and b, adding them if they have not been added. Returns false
+// if they were already in the same set.
+func (d *DisjointSet[T]) Union(a, b T) bool {
+	i, ok := d.index[a]
+	if !ok {
+		i = d.add(a)
+	}
+	j, ok := d.index[b]
+	if !ok {
+		j = d.add(b)
+	}
+	i = d.root(i)
+	j = d.root(j)
+	if i == j {
+		return false
+	}
+	// Hang the shorter tree below the taller one so that paths stay short.
+	if d.rank[i] < d.rank[j] {
+		i, j = j, i
+	}
+	d.parent[j] = i
+	if d.rank[i] == d.rank[j] {
+		d.rank[i]++
+	}
+	d.size[i] += d.size[j]
+	// Splice the two cycles together.
+	d.next[i], d.next[j] = d.next[j], d.next[i]
+	d.numSets--
+	return true
+}
+
+// root returns the index of the representative of the set containing the item at index i.
+func (d *DisjointSet[T]) root(i int) int {
+	r := i
+	for d.parent[r] != r {
+		r = d.parent[r]
+	}
+	// Point everything on the path directly at the root to speed up later calls.
+	for d.parent[i] != r {
+		d.parent[i], i = r, d.parent[i]
+	}
+	return r
+}
+
+// Find returns the representative of the set containing x. Two items are in the same set if and
+// only if they have the same representative. The representative of a set changes only when it is
+// merged with another set.
+func (d *DisjointSet[T]) Find(x T) T {
+	i, ok := d.index[x]
+	if !ok {
+		return x
+	}
+	return d.items[d.root(i)]
+}
+
+// Connected returns true if a and b are in the same set.
+func (d *DisjointSet[T]) Connected(a, b T) bool {
+	if a == b {
+		return true
+	}
+	i, ok := d.index[a]
+	if !ok {
+		return false
+	}
+	j, ok := d.index[b]
+	if !ok {
+		return false
+	}
+	return d.root(i) == d.root(j)
+}
+
+// SetSize returns the number of items in the set containing x.
+func (d *DisjointSet[T]) SetSize(x T) int {
+	i, ok := d.index[x]
+	if !ok {
+		return 1
+	}
+	return d.size[d.root(i)]
+}
+
+// Members returns an iterator over the items in the same set as x, including x itself, in no
+// particular order.
+//
+// The iterator is invalidated if the DisjointSet is modified.
+func (d *DisjointSet[T]) Members(x T) iterator.Iterator[T] {
+	i, ok := d.index[x]
+	if !ok {
+		return iterator.Slice([]T{x})
+	}
+	return &membersIterator[T]{d: d, start: i, i: i}
+}
+
+type membersIterator[T comparable] struct {
+	d     *DisjointSet[T]
+	start int
+	i     int
+	done  bool
+}
+
+func (iter *membersIterator[T]) Next() (T, bool) {
+	if iter.done {
+		var zero T
+		return zero, false
+	}
+	item := iter.d.items[iter.i]
+	iter.i = iter.d.next[iter.i]
+	iter.done = iter.i == iter.start
+	return item, true
+}
+
+// Sets returns an iterator over the sets among the items that have been added, each as a slice of
+// its members, in no particular order.
+//
+// The iterator is invalidated if the DisjointSet is modified.
+func (d *DisjointSet[T]) Sets() iterator.Iterator[[]T] {
+	return &setsIterator[T]{d: d}
+}
+
+type setsIterator[T comparable] struct {
+	d *DisjointSet[T]
+	i int
+}
+
+func (iter *setsIterator[T]) Next() ([]T, bool) {
+	for ; iter.i < len(iter.d.items); iter.i++ {
+		if iter.d.parent[iter.i] == iter.i {
+			set := iterator.Collect(iter.d.Members(iter.d.items[iter.i]))
+			iter.i++
+			return set, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,120 @@
+package unionfind
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/bradenaw/juniper/internal/fuzz"
+	"github.com/bradenaw/juniper/internal/require2"
+	"github.com/bradenaw/juniper/iterator"
+	"github.com/bradenaw/juniper/xslices"
+)
+
+func FuzzDisjointSet(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte) {
+		d := New[byte]()
+		// Each added item's set label. Union relabels one whole set.
+		oracle := make(map[byte]int)
+		nextLabel := 0
+		label := func(x byte) int {
+			l, ok := oracle[x]
+			if !ok {
+				return -1 - int(x)
+			}
+			return l
+		}
+		add := func(x byte) {
+			if _, ok := oracle[x]; !ok {
+				oracle[x] = nextLabel
+				nextLabel++
+			}
+		}
+		members := func(x byte) []byte {
+			if _, ok := oracle[x]; !ok {
+				return []byte{x}
+			}
+			var out []byte
+			for y, l := range oracle {
+				if l == oracle[x] {
+					out = append(out, y)
+				}
+			}
+			return out
+		}
+		sorted := func(s []byte) []byte {
+			s = xslices.Clone(s)
+			sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+			return s
+		}
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				require2.Equal(t, len(oracle), d.Len())
+				labels := make(map[int]struct{})
+				for _, l := range oracle {
+					labels[l] = struct{}{}
+				}
+				require2.Equal(t, len(labels), d.NumSets())
+
+				sets := iterator.Collect(d.Sets())
+				require2.Equal(t, len(labels), len(sets))
+				seen := 0
+				for _, set := range sets {
+					require2.SlicesEqual(t, sorted(members(set[0])), sorted(set))
+					seen += len(set)
+				}
+				require2.Equal(t, len(oracle), seen)
+			},
+			func(x byte) {
+				t.Logf("Add(%d)", x)
+				_, ok := oracle[x]
+				require2.Equal(t, !ok, d.Add(x))
+				add(x)
+			},
+			func(x, y byte) {
+				t.Logf("Union(%d, %d)", x, y)
+				add(x)
+				add(y)
+				lx, ly := oracle[x], oracle[y]
+				require2.Equal(t, lx != ly, d.Union(x, y))
+				for z, l := range oracle {
+					if l == ly {
+						oracle[z] = lx
+					}
+				}
+			},
+			func(x, y byte) {
+				t.Logf("Connected(%d, %d)", x, y)
+				require2.Equal(t, x == y || label(x) == label(y), d.Connected(x, y))
+				require2.Equal(t, d.Connected(x, y), d.Find(x) == d.Find(y))
+			},
+			func(x byte) {
+				t.Logf("SetSize(%d)", x)
+				require2.Equal(t, len(members(x)), d.SetSize(x))
+				require2.SlicesEqual(t, sorted(members(x)), sorted(iterator.Collect(d.Members(x))))
+				require2.Equal(t, label(x), label(d.Find(x)))
+			},
+		)
+	})
+}
+
+func ExampleDisjointSet() {
+	d := New[string]()
+	d.Union("alice@example.com", "alice@work.example.com")
+	d.Union("bob@example.com", "robert@example.com")
+	d.Union("alice@work.example.com", "a.smith@example.com")
+	d.Add("carol@example.com")
+
+	fmt.Println(d.NumSets())
+	fmt.Println(d.Connected("alice@example.com", "a.smith@example.com"))
+	fmt.Println(d.Connected("alice@example.com", "bob@example.com"))
+	fmt.Println(d.SetSize("alice@example.com"))
+
+	// Output:
+	// 3
+	// true
+	// false
+	// 3
+}
@@ -0,0 +1,180 @@
+// Package window contains sliding windows that maintain an aggregate, like the sum or maximum, of
+// the most recent values pushed to them.
+package window
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bradenaw/juniper/container/deque"
+)
+
+var errWindowEmpty = errors.New("window is empty")
+
+// Window holds a sequence of values, which are pushed at one end and evicted from the other, and
+// can report the combination of all of them.
+//
+// combine must be associative, and identity must be its identity: combine(identity, x) and
+// combine(x, identity) must both be x. combine does not need to be commutative or have an inverse,
+// and values are always combined from oldest to newest. For example, combine can be + with identity
+// 0, or max with identity math.MinInt.
+//
+// Push, Evict, and Aggregate take amortized O(1) time and calls to combine.
+type Window[T any] struct {
+	identity T
+	combine  func(a, b T) T
+
+	// The window is kept as two stacks. Values are pushed onto back, and when front is empty they
+	// are all moved onto front, reversing their order so that the oldest can be evicted from the
+	// top.
+	//
+	// Each entry of front holds the combination of itself and everything newer in front, so that
+	// evicting doesn't require recomputing anything. backAgg is the combination of everything in
+	// back.
+	front   deque.Deque[frontEntry[T]]
+	back    deque.Deque[T]
+	backAgg T
+}
+
+type frontEntry[T any] struct {
+	value T
+	agg   T
+}
+
+// New returns an empty Window that aggregates with combine and identity.
+func New[T any](identity T, combine func(a, b T) T) *Window[T] {
+	return &Window[T]{
+		identity: identity,
+		combine:  combine,
+		backAgg:  identity,
+	}
+}
+
+// Len returns the number of values in the window.
+func (w *Window[T]) Len() int {
+	return w.front.Len() + w.back.Len()
+}
+
+// Push adds x to the window as the newest value.
+func (w *Window[T]) Push(x T) {
+	w.back.PushBack(x)
+	w.backAgg = w.combine(w.backAgg, x)
+}
+
+// Oldest returns the oldest value in the window, which is the next to be evicted. It panics if the
+// window is empty.
+func (w *Window[T]) Oldest() T {
+	if w.front.Len() > 0 {
+		return w.front.Back().value
+	}
+	if w.back.Len() == 0 {
+		panic(errWindowEmpty)
+	}
+	return w.back.Front()
+}
+
+// Evict removes and returns the oldest value in the window. It panics if the window is empty.
+func (w *Window[T]) Evict() T {
+	if w.front.Len() == 0 {
+		if w.back.Len() == 0 {
+			panic(errWindowEmpty)
+		}
+		agg := w.identity
+		for w.back.Len() > 0 {
+			x := w.back.PopBack()
+			agg = w.combine(x, agg)
+			w.front.PushBack(frontEntry[T]{value: x, agg: agg})
+		}
+		w.backAgg = w.identity
+	}
+	return w.front.PopBack().value
+}
+
+// Aggregate returns the combination of all of the values in the window from oldest to newest, or
+// identity if the window is empty.
+func (w *Window[T]) Aggregate() T {
+	if w.front.Len() == 0 {
+		return w.backAgg
+	}
+	return w.combine(w.front.Back().agg, w.backAgg)
+}
+
+// CountWindow is a Window of the n most recently pushed values.
+type CountWindow[T any] struct {
+	w Window[T]
+	n int
+}
+
+// NewCountWindow returns an empty CountWindow that holds the n most recent values and aggregates
+// with combine and identity, which have the same requirements as in New.
+func NewCountWindow[T any](n int, identity T, combine func(a, b T) T) *CountWindow[T] {
+	if n <= 0 {
+		panic("CountWindow size must be positive")
+	}
+	return &CountWindow[T]{w: *New(identity, combine), n: n}
+}
+
+// Len returns the number of values in the window, which is at most n.
+func (w *CountWindow[T]) Len() int {
+	return w.w.Len()
+}
+
+// Push adds x to the window as the newest value, evicting the oldest if the window already has n
+// values.
+func (w *CountWindow[T]) Push(x T) {
+	if w.w.Len() == w.n {
+		w.w.Evict()
+	}
+	w.w.Push(x)
+}
+
+// Aggregate returns the combination of all of the values in the window from oldest to newest, or
+// identity if the window is empty.
+func (w *CountWindow[T]) Aggregate() T {
+	return w.w.Aggregate()
+}
+
+// TimeWindow is a Window of the values pushed within a duration of the current time.
+//
+// Times are passed explicitly rather than read from a clock, so values can be pushed with the time
+// at which they happened, for example a request's start time.
+type TimeWindow[T any] struct {
+	w     Window[T]
+	times deque.Deque[time.Time]
+	d     time.Duration
+}
+
+// NewTimeWindow returns an empty TimeWindow that holds the values pushed within d and aggregates
+// with combine and identity, which have the same requirements as in New.
+func NewTimeWindow[T any](d time.Duration, identity T, combine func(a, b T) T) *TimeWindow[T] {
+	return &TimeWindow[T]{w: *New(identity, combine), d: d}
+}
+
+// Len returns the number of values in the window, including any that have expired but have not yet
+// been evicted by Push or Aggregate.
+func (w *TimeWindow[T]) Len() int {
+	return w.w.Len()
+}
+
+// Push adds x to the window as the newest value, pushed at time t, and evicts any values that are
+// older than t minus the window's duration. t must not be before the time of any earlier Push.
+func (w *TimeWindow[T]) Push(t time.Time, x T) {
+	w.evict(t)
+	w.w.Push(x)
+	w.times.PushBack(t)
+}
+
+// Aggregate evicts any values that are older than now minus the window's duration, and then returns
+// the combination of the remaining values from oldest to newest, or identity if there are none.
+func (w *TimeWindow[T]) Aggregate(now time.Time) T {
+	w.evict(now)
+	return w.w.Aggregate()
+}
+
+func (w *TimeWindow[T]) evict(now time.Time) {
+	cutoff := now.Add(-w.d)
+	for w.times.Len() > 0 && w.times.Front().Before(cutoff) {
+		w.times.PopFront()
+		w.w.Evict()
+	}
+}
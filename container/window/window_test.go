@@ -0,0 +1,109 @@
+package window
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bradenaw/juniper/internal/fuzz"
+	"github.com/bradenaw/juniper/internal/require2"
+)
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func FuzzWindow(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte) {
+		// Concatenation isn't commutative, so this also checks that values are combined in order.
+		w := New("", func(a, b string) string { return a + b })
+		var oracle []string
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				require2.Equal(t, len(oracle), w.Len())
+				require2.Equal(t, strings.Join(oracle, ""), w.Aggregate())
+				if len(oracle) > 0 {
+					require2.Equal(t, oracle[0], w.Oldest())
+				}
+			},
+			func(x byte) {
+				s := fmt.Sprintf("%d,", x)
+				t.Logf("Push(%q)", s)
+				w.Push(s)
+				oracle = append(oracle, s)
+			},
+			func() {
+				if len(oracle) == 0 {
+					return
+				}
+				t.Logf("Evict()")
+				require2.Equal(t, oracle[0], w.Evict())
+				oracle = oracle[1:]
+			},
+		)
+	})
+}
+
+func TestCountWindow(t *testing.T) {
+	w := NewCountWindow(3, math.MinInt, max)
+	require2.Equal(t, math.MinInt, w.Aggregate())
+
+	var maxes []int
+	for _, x := range []int{3, 1, 4, 1, 5, 9, 2, 6, 5, 3, 5} {
+		w.Push(x)
+		maxes = append(maxes, w.Aggregate())
+	}
+	require2.SlicesEqual(t, []int{3, 3, 4, 4, 5, 9, 9, 9, 6, 6, 5}, maxes)
+	require2.Equal(t, 3, w.Len())
+}
+
+func TestTimeWindow(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+
+	w := NewTimeWindow(10*time.Second, math.MinInt, max)
+	w.Push(at(0), 7)
+	w.Push(at(3), 2)
+	w.Push(at(8), 5)
+	require2.Equal(t, 7, w.Aggregate(at(10)))
+	require2.Equal(t, 5, w.Aggregate(at(11)))
+	require2.Equal(t, 2, w.Len())
+	w.Push(at(14), 1)
+	require2.Equal(t, 5, w.Aggregate(at(18)))
+	require2.Equal(t, 1, w.Aggregate(at(19)))
+	require2.Equal(t, math.MinInt, w.Aggregate(at(30)))
+	require2.Equal(t, 0, w.Len())
+}
+
+func ExampleTimeWindow() {
+	type counts struct {
+		errors int
+		total  int
+	}
+	add := func(a, b counts) counts { return counts{a.errors + b.errors, a.total + b.total} }
+	w := NewTimeWindow(time.Minute, counts{}, add)
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 120; i++ {
+		// Every 10th request fails for the first minute, and every 4th after that.
+		failed := (i < 60 && i%10 == 0) || (i >= 60 && i%4 == 0)
+		c := counts{total: 1}
+		if failed {
+			c.errors = 1
+		}
+		w.Push(start.Add(time.Duration(i)*time.Second), c)
+	}
+
+	c := w.Aggregate(start.Add(119 * time.Second))
+	fmt.Printf("%d/%d errors in the last minute\n", c.errors, c.total)
+
+	// Output:
+	// 15/61 errors in the last minute
+}
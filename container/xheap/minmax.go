@@ -0,0 +1,224 @@
+package xheap
+
+import (
+	"math/bits"
+
+	"github.com/bradenaw/juniper/internal/heap"
+	"github.com/bradenaw/juniper/iterator"
+	"github.com/bradenaw/juniper/xslices"
+	"github.com/bradenaw/juniper/xsort"
+)
+
+// MinMaxHeap is a min-max heap (https://en.wikipedia.org/wiki/Min-max_heap), also known as a
+// double-ended priority queue. It provides constant-time access to both the minimum and the maximum
+// element, and logarithmic-time removal of either.
+//
+// Push, PopMin, and PopMax take amortized O(log(n)) time where n is the number of items in the
+// heap.
+//
+// Len, PeekMin, and PeekMax take O(1) time.
+type MinMaxHeap[T any] struct {
+	// Indirect here so that MinMaxHeap behaves as a reference type, like the map builtin.
+	inner *minMaxHeap[T]
+}
+
+// NewMinMax returns a new MinMaxHeap which uses less to determine the minimum and maximum elements.
+//
+// The elements from initial are added to the heap. initial is modified by NewMinMax and utilized by
+// the MinMaxHeap, so it should not be used after passing to NewMinMax. Passing initial is faster
+// (O(n)) than creating an empty heap and pushing each item (O(n * log(n))).
+func NewMinMax[T any](less xsort.Less[T], initial []T) MinMaxHeap[T] {
+	h := &minMaxHeap[T]{
+		less: less,
+		a:    initial,
+	}
+	for i := len(initial)/2 - 1; i >= 0; i-- {
+		h.trickleDown(i)
+	}
+	return MinMaxHeap[T]{inner: h}
+}
+
+func NewMinMaxCmp[T any](compare func(T, T) int, initial []T) MinMaxHeap[T] {
+	return NewMinMax(func(a, b T) bool {
+		return compare(a, b) < 0
+	}, initial)
+}
+
+// Len returns the current number of elements in the heap.
+func (h MinMaxHeap[T]) Len() int {
+	return len(h.inner.a)
+}
+
+// Grow allocates sufficient space to add n more elements without needing to reallocate.
+func (h MinMaxHeap[T]) Grow(n int) {
+	h.inner.a = xslices.Grow(h.inner.a, n)
+}
+
+// Shrink reallocates the backing buffer for h, if necessary, so that it fits only the current size
+// plus at most n extra items.
+func (h MinMaxHeap[T]) Shrink(n int) {
+	h.inner.a = xslices.Shrink(h.inner.a, n)
+}
+
+// Push adds item to the heap.
+func (h MinMaxHeap[T]) Push(item T) {
+	h.inner.a = append(h.inner.a, item)
+	h.inner.bubbleUp(len(h.inner.a) - 1)
+	h.inner.gen++
+}
+
+// PopMin removes and returns the minimum item in the heap. It panics if h.Len()==0.
+func (h MinMaxHeap[T]) PopMin() T {
+	return h.inner.removeAt(0)
+}
+
+// PopMax removes and returns the maximum item in the heap. It panics if h.Len()==0.
+func (h MinMaxHeap[T]) PopMax() T {
+	return h.inner.removeAt(h.inner.maxIndex())
+}
+
+// PeekMin returns the minimum item in the heap. It panics if h.Len()==0.
+func (h MinMaxHeap[T]) PeekMin() T {
+	return h.inner.a[0]
+}
+
+// PeekMax returns the maximum item in the heap. It panics if h.Len()==0.
+func (h MinMaxHeap[T]) PeekMax() T {
+	return h.inner.a[h.inner.maxIndex()]
+}
+
+// Iterate iterates over the elements of the heap in arbitrary order.
+//
+// The iterator panics if the heap has been modified since iteration started.
+func (h MinMaxHeap[T]) Iterate() iterator.Iterator[T] {
+	return &minMaxHeapIterator[T]{h: h.inner, gen: -1}
+}
+
+// Elements at even depths (the root is depth 0) are less than or equal to all of their
+// descendants, and elements at odd depths are greater than or equal to all of their descendants.
+type minMaxHeap[T any] struct {
+	less xsort.Less[T]
+	a    []T
+	gen  int
+}
+
+func (h *minMaxHeap[T]) maxIndex() int {
+	switch len(h.a) {
+	case 0:
+		panic("heap index out of range")
+	case 1:
+		return 0
+	case 2:
+		return 1
+	}
+	if h.less(h.a[1], h.a[2]) {
+		return 2
+	}
+	return 1
+}
+
+func (h *minMaxHeap[T]) removeAt(i int) T {
+	var zero T
+	item := h.a[i]
+	last := len(h.a) - 1
+	h.a[i] = h.a[last]
+	// In case T is a pointer, clear this out to keep the ref from being live.
+	h.a[last] = zero
+	h.a = h.a[:last]
+	if i < len(h.a) {
+		h.trickleDown(i)
+	}
+	h.gen++
+	return item
+}
+
+// before returns true if a belongs closer to the root than b on a level that is min if isMin and
+// max otherwise.
+func (h *minMaxHeap[T]) before(isMin bool, a, b int) bool {
+	if isMin {
+		return h.less(h.a[a], h.a[b])
+	}
+	return h.less(h.a[b], h.a[a])
+}
+
+func (h *minMaxHeap[T]) swap(i, j int) {
+	h.a[i], h.a[j] = h.a[j], h.a[i]
+}
+
+func (h *minMaxHeap[T]) bubbleUp(i int) {
+	if i == 0 {
+		return
+	}
+	isMin := isMinLevel(i)
+	p := minMaxParent(i)
+	if h.before(!isMin, i, p) {
+		// i belongs on the opposite kind of level, so swap it up to the parent's level and continue
+		// from there.
+		h.swap(i, p)
+		i = p
+		isMin = !isMin
+	}
+	for i > 2 {
+		gp := minMaxParent(minMaxParent(i))
+		if !h.before(isMin, i, gp) {
+			return
+		}
+		h.swap(i, gp)
+		i = gp
+	}
+}
+
+func (h *minMaxHeap[T]) trickleDown(i int) {
+	isMin := isMinLevel(i)
+	for {
+		// Find the child or grandchild that most belongs closer to the root.
+		first := 2*i + 1
+		if first >= len(h.a) {
+			return
+		}
+		m := first
+		for _, j := range [...]int{first + 1, 2*first + 1, 2*first + 2, 2*first + 3, 2*first + 4} {
+			if j < len(h.a) && h.before(isMin, j, m) {
+				m = j
+			}
+		}
+
+		if !h.before(isMin, m, i) {
+			return
+		}
+		h.swap(m, i)
+		if m <= first+1 {
+			// m is a child, so it has no children of its own that can be out of place.
+			return
+		}
+		p := minMaxParent(m)
+		if h.before(!isMin, m, p) {
+			h.swap(m, p)
+		}
+		i = m
+	}
+}
+
+func minMaxParent(i int) int {
+	return (i - 1) / 2
+}
+
+func isMinLevel(i int) bool {
+	return (bits.Len(uint(i+1))-1)%2 == 0
+}
+
+type minMaxHeapIterator[T any] struct {
+	h     *minMaxHeap[T]
+	inner iterator.Iterator[T]
+	gen   int
+}
+
+func (iter *minMaxHeapIterator[T]) Next() (T, bool) {
+	if iter.gen == -1 {
+		iter.gen = iter.h.gen
+		iter.inner = iterator.Slice(iter.h.a)
+	} else if iter.gen != iter.h.gen {
+		panic(heap.ErrHeapModified)
+	}
+	return iter.inner.Next()
+}
@@ -0,0 +1,259 @@
+package xheap
+
+import (
+	"github.com/bradenaw/juniper/internal/heap"
+	"github.com/bradenaw/juniper/iterator"
+	"github.com/bradenaw/juniper/xsort"
+)
+
+// PairingHeap is a min-heap implemented as a pairing heap
+// (https://en.wikipedia.org/wiki/Pairing_heap). Unlike Heap, it returns a handle for each pushed
+// item that can later be used to decrease its priority or remove it, and two PairingHeaps can be
+// melded together in constant time.
+//
+// Push, Peek, Meld, and Len take O(1) time.
+//
+// Pop and Remove take amortized O(log(n)) time where n is the number of items in the heap.
+//
+// DecreaseKey takes amortized O(log(n)) time.
+type PairingHeap[T any] struct {
+	// Indirect here so that PairingHeap behaves as a reference type, like the map builtin.
+	inner *pairingHeap[T]
+}
+
+type pairingHeap[T any] struct {
+	less xsort.Less[T]
+	root *PairingNode[T]
+	size int
+	gen  int
+}
+
+// PairingNode is a handle to an item in a PairingHeap.
+type PairingNode[T any] struct {
+	item T
+	// The first of this node's children.
+	child *PairingNode[T]
+	// The next of this node's siblings.
+	next *PairingNode[T]
+	// The previous of this node's siblings, or its parent if this node is the first child.
+	prev *PairingNode[T]
+	in   bool
+}
+
+// Item returns the item that n holds.
+func (n *PairingNode[T]) Item() T {
+	return n.item
+}
+
+// NewPairingHeap returns a new, empty PairingHeap which uses less to determine the minimum element.
+func NewPairingHeap[T any](less xsort.Less[T]) PairingHeap[T] {
+	return PairingHeap[T]{
+		inner: &pairingHeap[T]{less: less},
+	}
+}
+
+func NewPairingHeapCmp[T any](compare func(T, T) int) PairingHeap[T] {
+	return NewPairingHeap(func(a, b T) bool {
+		return compare(a, b) < 0
+	})
+}
+
+// Len returns the current number of elements in the heap.
+func (h PairingHeap[T]) Len() int {
+	return h.inner.size
+}
+
+// Push adds item to the heap and returns a handle that can be passed to DecreaseKey and Remove.
+func (h PairingHeap[T]) Push(item T) *PairingNode[T] {
+	n := &PairingNode[T]{item: item, in: true}
+	h.inner.root = h.inner.link(h.inner.root, n)
+	h.inner.size++
+	h.inner.gen++
+	return n
+}
+
+// Pop removes and returns the minimum item in the heap. It panics if h.Len()==0.
+func (h PairingHeap[T]) Pop() T {
+	n := h.inner.root
+	h.inner.root = h.inner.mergePairs(n.child)
+	h.inner.size--
+	h.inner.gen++
+	return n.clear()
+}
+
+// Peek returns the minimum item in the heap. It panics if h.Len()==0.
+func (h PairingHeap[T]) Peek() T {
+	return h.inner.root.item
+}
+
+// DecreaseKey replaces the item held by n with item, which must not be greater than the item n
+// already holds. n must be a handle returned from Push on h or on a heap that has since been melded
+// into h.
+//
+// DecreaseKey panics if item is greater than n.Item() or if n has been removed from the heap.
+func (h PairingHeap[T]) DecreaseKey(n *PairingNode[T], item T) {
+	if !n.in {
+		panic("DecreaseKey on a node no longer in the heap")
+	}
+	if h.inner.less(n.item, item) {
+		panic("DecreaseKey with a greater item")
+	}
+	n.item = item
+	h.inner.gen++
+	if n == h.inner.root {
+		return
+	}
+	n.cut()
+	h.inner.root = h.inner.link(h.inner.root, n)
+}
+
+// Remove removes n from the heap. n must be a handle returned from Push on h or on a heap that has
+// since been melded into h. Does nothing if n has already been removed.
+func (h PairingHeap[T]) Remove(n *PairingNode[T]) {
+	if !n.in {
+		return
+	}
+	if n == h.inner.root {
+		h.Pop()
+		return
+	}
+	n.cut()
+	h.inner.root = h.inner.link(h.inner.root, h.inner.mergePairs(n.child))
+	h.inner.size--
+	h.inner.gen++
+	n.clear()
+}
+
+// Meld moves all of the items of other into h, leaving other empty. Handles to items in other
+// remain valid and must be used with h afterwards. h and other must use the same less function.
+func (h PairingHeap[T]) Meld(other PairingHeap[T]) {
+	if h.inner == other.inner {
+		return
+	}
+	h.inner.root = h.inner.link(h.inner.root, other.inner.root)
+	h.inner.size += other.inner.size
+	h.inner.gen++
+	other.inner.root = nil
+	other.inner.size = 0
+	other.inner.gen++
+}
+
+// Iterate iterates over the elements of the heap in arbitrary order.
+//
+// The iterator panics if the heap has been modified since iteration started.
+func (h PairingHeap[T]) Iterate() iterator.Iterator[T] {
+	return &pairingHeapIterator[T]{h: h.inner, gen: -1}
+}
+
+// link makes the greater of the two roots a and b the first child of the other and returns the new
+// root. Either may be nil.
+func (h *pairingHeap[T]) link(a, b *PairingNode[T]) *PairingNode[T] {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if h.less(b.item, a.item) {
+		a, b = b, a
+	}
+	b.prev = a
+	b.next = a.child
+	if a.child != nil {
+		a.child.prev = b
+	}
+	a.child = b
+	return a
+}
+
+// mergePairs links together the sibling list starting at first using the standard two-pass
+// strategy and returns the resulting root.
+func (h *pairingHeap[T]) mergePairs(first *PairingNode[T]) *PairingNode[T] {
+	if first == nil {
+		return nil
+	}
+	// First pass: link adjacent pairs left to right, accumulating the results in reverse order
+	// through next.
+	var acc *PairingNode[T]
+	curr := first
+	for curr != nil {
+		a := curr
+		b := a.next
+		if b == nil {
+			curr = nil
+		} else {
+			curr = b.next
+			b.prev = nil
+			b.next = nil
+		}
+		a.prev = nil
+		a.next = nil
+		a = h.link(a, b)
+		a.next = acc
+		acc = a
+	}
+	// Second pass: link the results right to left.
+	result := acc
+	acc = acc.next
+	result.next = nil
+	for acc != nil {
+		n := acc
+		acc = acc.next
+		n.next = nil
+		result = h.link(result, n)
+	}
+	return result
+}
+
+// cut detaches n and its subtree from its parent and siblings.
+func (n *PairingNode[T]) cut() {
+	if n.prev.child == n {
+		n.prev.child = n.next
+	} else {
+		n.prev.next = n.next
+	}
+	if n.next != nil {
+		n.next.prev = n.prev
+	}
+	n.prev = nil
+	n.next = nil
+}
+
+func (n *PairingNode[T]) clear() T {
+	item := n.item
+	n.child = nil
+	n.next = nil
+	n.prev = nil
+	n.in = false
+	return item
+}
+
+type pairingHeapIterator[T any] struct {
+	h     *pairingHeap[T]
+	stack []*PairingNode[T]
+	gen   int
+}
+
+func (iter *pairingHeapIterator[T]) Next() (T, bool) {
+	if iter.gen == -1 {
+		iter.gen = iter.h.gen
+		if iter.h.root != nil {
+			iter.stack = append(iter.stack, iter.h.root)
+		}
+	} else if iter.gen != iter.h.gen {
+		panic(heap.ErrHeapModified)
+	}
+	if len(iter.stack) == 0 {
+		var zero T
+		return zero, false
+	}
+	n := iter.stack[len(iter.stack)-1]
+	iter.stack = iter.stack[:len(iter.stack)-1]
+	if n.next != nil {
+		iter.stack = append(iter.stack, n.next)
+	}
+	if n.child != nil {
+		iter.stack = append(iter.stack, n.child)
+	}
+	return n.item, true
+}
@@ -0,0 +1,76 @@
+package xheap
+
+import (
+	"github.com/bradenaw/juniper/internal/heap"
+	"github.com/bradenaw/juniper/iterator"
+	"github.com/bradenaw/juniper/xsort"
+)
+
+// TopK incrementally collects the k minimum items according to less from the items added to it. To
+// collect the k maximum items instead, use xsort.Reverse.
+//
+// TopKs can be merged, so it's possible to collect partial results from several goroutines, for
+// example inside of parallel.Do, and combine them at the end.
+//
+// Add takes O(log(k)) time and the TopK uses O(k) space regardless of how many items are added.
+type TopK[T any] struct {
+	less xsort.Less[T]
+	k    int
+	// A max-heap of the k minimum items seen so far, so that the greatest of them is the one to
+	// evict when a smaller item comes along.
+	h heap.Heap[T]
+}
+
+// NewTopK returns a TopK that keeps the k minimum items according to less.
+func NewTopK[T any](less xsort.Less[T], k int) *TopK[T] {
+	return &TopK[T]{
+		less: less,
+		k:    k,
+		h:    heap.New(heap.Less[T](xsort.Reverse(less)), func(a T, i int) {}, nil),
+	}
+}
+
+// Len returns the number of items currently held, which is at most k.
+func (t *TopK[T]) Len() int {
+	return t.h.Len()
+}
+
+// Add adds item, keeping it only if it is among the k minimum items added so far.
+func (t *TopK[T]) Add(item T) {
+	if t.h.Len() < t.k {
+		t.h.Push(item)
+	} else if t.k > 0 && t.less(item, t.h.Peek()) {
+		t.h.UpdateAt(0, item)
+	}
+}
+
+// AddAll adds all of the items yielded by iter.
+func (t *TopK[T]) AddAll(iter iterator.Iterator[T]) {
+	for {
+		item, ok := iter.Next()
+		if !ok {
+			return
+		}
+		t.Add(item)
+	}
+}
+
+// Merge adds all of the items held by other to t. other is not modified. Merging t into itself
+// does nothing, since t already holds the k minimum of its own items.
+func (t *TopK[T]) Merge(other *TopK[T]) {
+	if other == t {
+		return
+	}
+	t.AddAll(other.h.Iterate())
+}
+
+// Sorted returns the items held by t in sorted order according to less. If fewer than k items have
+// been added, all of them are returned.
+func (t *TopK[T]) Sorted() []T {
+	out := make([]T, 0, t.h.Len())
+	out = iterator.Reduce(t.h.Iterate(), out, func(out []T, item T) []T {
+		return append(out, item)
+	})
+	xsort.Slice(out, t.less)
+	return out
+}
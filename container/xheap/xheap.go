@@ -90,11 +90,20 @@ type KP[K any, P any] struct {
 // PriorityQueue is a queue that yields items in increasing order of priority.
 type PriorityQueue[K comparable, P any] struct {
 	// Indirect here so that Heap behaves as a reference type, like the map builtin.
-	inner *heap.Heap[KP[K, P]]
+	inner *heap.Heap[pqItem[K, P]]
 	m     map[K]int
+	// The next sequence number to hand out. Only used for tie-breaking by stable priority queues.
+	seq *uint64
+}
+
+type pqItem[K any, P any] struct {
+	kp  KP[K, P]
+	seq uint64
 }
 
 // NewPriorityQueue returns a new PriorityQueue which uses less to determine the minimum element.
+// Items with equal priorities are yielded in arbitrary order, see NewStablePriorityQueue if this is
+// undesirable.
 //
 // The elements from initial are added to the priority queue. initial is modified by
 // NewPriorityQueue and utilized by the PriorityQueue, so it should not be used after passing to
@@ -108,9 +117,62 @@ type PriorityQueue[K comparable, P any] struct {
 func NewPriorityQueue[K comparable, P any](
 	less xsort.Less[P],
 	initial []KP[K, P],
+) PriorityQueue[K, P] {
+	return newPriorityQueue(
+		func(a, b pqItem[K, P]) bool {
+			return less(a.kp.P, b.kp.P)
+		},
+		initial,
+	)
+}
+
+func NewPriorityQueueCmp[K comparable, P any](
+	compare func(P, P) int,
+	initial []KP[K, P],
+) PriorityQueue[K, P] {
+	return NewPriorityQueue(func(a, b P) bool {
+		return compare(a, b) < 0
+	}, initial)
+}
+
+// NewStablePriorityQueue is like NewPriorityQueue, except that items with equal priorities are
+// yielded in first-in-first-out order. An item is considered inserted again when its priority is
+// changed by Update, and so goes behind the other items with the same priority.
+//
+// The items in initial are considered inserted in the order they appear.
+func NewStablePriorityQueue[K comparable, P any](
+	less xsort.Less[P],
+	initial []KP[K, P],
+) PriorityQueue[K, P] {
+	return newPriorityQueue(
+		func(a, b pqItem[K, P]) bool {
+			if less(a.kp.P, b.kp.P) {
+				return true
+			} else if less(b.kp.P, a.kp.P) {
+				return false
+			}
+			return a.seq < b.seq
+		},
+		initial,
+	)
+}
+
+func NewStablePriorityQueueCmp[K comparable, P any](
+	compare func(P, P) int,
+	initial []KP[K, P],
+) PriorityQueue[K, P] {
+	return NewStablePriorityQueue(func(a, b P) bool {
+		return compare(a, b) < 0
+	}, initial)
+}
+
+func newPriorityQueue[K comparable, P any](
+	less heap.Less[pqItem[K, P]],
+	initial []KP[K, P],
 ) PriorityQueue[K, P] {
 	h := PriorityQueue[K, P]{
-		m: make(map[K]int),
+		m:   make(map[K]int),
+		seq: new(uint64),
 	}
 	filtered := initial[:0]
 	for _, kp := range initial {
@@ -121,27 +183,25 @@ func NewPriorityQueue[K comparable, P any](
 		h.m[kp.K] = -1
 		filtered = append(filtered, kp)
 	}
-	initial = filtered
+	items := make([]pqItem[K, P], len(filtered))
+	for i, kp := range filtered {
+		items[i] = pqItem[K, P]{kp: kp, seq: h.nextSeq()}
+	}
 	inner := heap.New(
-		func(a, b KP[K, P]) bool {
-			return less(a.P, b.P)
-		},
-		func(x KP[K, P], i int) {
-			h.m[x.K] = i
+		less,
+		func(x pqItem[K, P], i int) {
+			h.m[x.kp.K] = i
 		},
-		initial,
+		items,
 	)
 	h.inner = &inner
 	return h
 }
 
-func NewPriorityQueueCmp[K comparable, P any](
-	compare func(P, P) int,
-	initial []KP[K, P],
-) PriorityQueue[K, P] {
-	return NewPriorityQueue(func(a, b P) bool {
-		return compare(a, b) < 0
-	}, initial)
+func (h PriorityQueue[K, P]) nextSeq() uint64 {
+	seq := *h.seq
+	*h.seq++
+	return seq
 }
 
 // Len returns the current number of elements in the priority queue.
@@ -156,24 +216,25 @@ func (h PriorityQueue[K, P]) Grow(n int) {
 
 // Update updates the priority of k to p, or adds it to the priority queue if not present.
 func (h PriorityQueue[K, P]) Update(k K, p P) {
+	item := pqItem[K, P]{kp: KP[K, P]{k, p}, seq: h.nextSeq()}
 	idx, ok := h.m[k]
 	if ok {
-		h.inner.UpdateAt(idx, KP[K, P]{k, p})
+		h.inner.UpdateAt(idx, item)
 	} else {
-		h.inner.Push(KP[K, P]{k, p})
+		h.inner.Push(item)
 	}
 }
 
 // Pop removes and returns the lowest-P item in the priority queue. It panics if h.Len()==0.
 func (h PriorityQueue[K, P]) Pop() K {
 	item := h.inner.Pop()
-	delete(h.m, item.K)
-	return item.K
+	delete(h.m, item.kp.K)
+	return item.kp.K
 }
 
 // Peek returns the key of the lowest-P item in the priority queue. It panics if h.Len()==0.
 func (h PriorityQueue[K, P]) Peek() K {
-	return h.inner.Peek().K
+	return h.inner.Peek().kp.K
 }
 
 // Contains returns true if the given key is present in the priority queue.
@@ -186,7 +247,7 @@ func (h PriorityQueue[K, P]) Contains(k K) bool {
 func (h PriorityQueue[K, P]) Priority(k K) P {
 	idx, ok := h.m[k]
 	if ok {
-		return h.inner.Item(idx).P
+		return h.inner.Item(idx).kp.P
 	}
 	var zero P
 	return zero
@@ -202,9 +263,18 @@ func (h PriorityQueue[K, P]) Remove(k K) {
 	delete(h.m, k)
 }
 
-// Iterate iterates over the elements of the priority queue.
+// Iterate iterates over the elements of the priority queue in arbitrary order.
 //
 // The iterator panics if the priority queue has been modified since iteration started.
 func (h PriorityQueue[K, P]) Iterate() iterator.Iterator[K] {
-	return iterator.Map(h.inner.Iterate(), func(kp KP[K, P]) K { return kp.K })
+	return iterator.Map(h.inner.Iterate(), func(item pqItem[K, P]) K { return item.kp.K })
+}
+
+// IterateInOrder iterates over the elements of the priority queue in increasing order of priority,
+// that is the same order that repeated calls to Pop would return them in, without removing them.
+//
+// Each call to Next takes O(log(n)) time. The iterator panics if the priority queue has been
+// modified since iteration started.
+func (h PriorityQueue[K, P]) IterateInOrder() iterator.Iterator[K] {
+	return iterator.Map(h.inner.IterateOrdered(), func(item pqItem[K, P]) K { return item.kp.K })
 }
@@ -3,8 +3,10 @@ package xheap
 import (
 	"testing"
 
+	"github.com/bradenaw/juniper/internal/fuzz"
 	"github.com/bradenaw/juniper/internal/require2"
 	"github.com/bradenaw/juniper/iterator"
+	"github.com/bradenaw/juniper/xslices"
 	"github.com/bradenaw/juniper/xsort"
 )
 
@@ -135,3 +137,281 @@ func FuzzPriorityQueue(f *testing.F) {
 		}
 	})
 }
+
+func FuzzMinMaxHeap(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b1 []byte, b2 []byte) {
+		t.Logf("initial: %#v", b1)
+		h := NewMinMax(xsort.OrderedLess[byte], append([]byte{}, b1...))
+		oracle := append([]byte{}, b1...)
+		xsort.Slice(oracle, xsort.OrderedLess[byte])
+
+		fuzz.Operations(
+			b2,
+			func() { // check
+				require2.Equal(t, len(oracle), h.Len())
+				items := iterator.Collect(h.Iterate())
+				xsort.Slice(items, xsort.OrderedLess[byte])
+				require2.SlicesEqual(t, oracle, items)
+				if len(oracle) > 0 {
+					require2.Equal(t, oracle[0], h.PeekMin())
+					require2.Equal(t, oracle[len(oracle)-1], h.PeekMax())
+				}
+			},
+			func(x byte) {
+				t.Logf("Push(%#v)", x)
+				h.Push(x)
+				idx := xsort.Search(oracle, xsort.OrderedLess[byte], x)
+				oracle = xslices.Insert(oracle, idx, x)
+			},
+			func() {
+				if len(oracle) == 0 {
+					return
+				}
+				t.Logf("PopMin()")
+				require2.Equal(t, oracle[0], h.PopMin())
+				oracle = oracle[1:]
+			},
+			func() {
+				if len(oracle) == 0 {
+					return
+				}
+				t.Logf("PopMax()")
+				require2.Equal(t, oracle[len(oracle)-1], h.PopMax())
+				oracle = oracle[:len(oracle)-1]
+			},
+		)
+	})
+}
+
+func FuzzPairingHeap(f *testing.F) {
+	type handle struct {
+		node *PairingNode[byte]
+		heap int
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		heaps := [2]PairingHeap[byte]{
+			NewPairingHeap(xsort.OrderedLess[byte]),
+			NewPairingHeap(xsort.OrderedLess[byte]),
+		}
+		var handles []handle
+
+		oracle := func(i int) []byte {
+			var out []byte
+			for _, h := range handles {
+				if h.heap == i {
+					out = append(out, h.node.Item())
+				}
+			}
+			xsort.Slice(out, xsort.OrderedLess[byte])
+			return out
+		}
+		removeHandle := func(node *PairingNode[byte]) {
+			for i := range handles {
+				if handles[i].node == node {
+					handles = xslices.Remove(handles, i, 1)
+					return
+				}
+			}
+		}
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				for i, h := range heaps {
+					expected := oracle(i)
+					require2.Equal(t, len(expected), h.Len())
+					items := iterator.Collect(h.Iterate())
+					xsort.Slice(items, xsort.OrderedLess[byte])
+					require2.SlicesEqual(t, expected, items)
+					if len(expected) > 0 {
+						require2.Equal(t, expected[0], h.Peek())
+					}
+				}
+			},
+			func(which bool, x byte) {
+				i := 0
+				if which {
+					i = 1
+				}
+				t.Logf("heaps[%d].Push(%#v)", i, x)
+				handles = append(handles, handle{node: heaps[i].Push(x), heap: i})
+			},
+			func(which bool) {
+				i := 0
+				if which {
+					i = 1
+				}
+				if heaps[i].Len() == 0 {
+					return
+				}
+				t.Logf("heaps[%d].Pop()", i)
+				expected := oracle(i)[0]
+				for _, h := range handles {
+					if h.heap == i && h.node.Item() == expected {
+						// Which of several equal items gets popped is arbitrary, so just drop the
+						// first matching handle from the oracle.
+						require2.Equal(t, expected, heaps[i].Pop())
+						removeHandle(h.node)
+						return
+					}
+				}
+			},
+			func(idx int, delta byte) {
+				if len(handles) == 0 || idx < 0 {
+					return
+				}
+				h := handles[idx%len(handles)]
+				x := h.node.Item()
+				if delta > x {
+					delta = x
+				}
+				t.Logf("heaps[%d].DecreaseKey(%#v, %#v)", h.heap, x, x-delta)
+				heaps[h.heap].DecreaseKey(h.node, x-delta)
+			},
+			func(idx int) {
+				if len(handles) == 0 || idx < 0 {
+					return
+				}
+				h := handles[idx%len(handles)]
+				t.Logf("heaps[%d].Remove(%#v)", h.heap, h.node.Item())
+				heaps[h.heap].Remove(h.node)
+				removeHandle(h.node)
+				heaps[h.heap].Remove(h.node)
+			},
+			func(which bool) {
+				i := 0
+				if which {
+					i = 1
+				}
+				t.Logf("heaps[%d].Meld(heaps[%d])", i, 1-i)
+				heaps[i].Meld(heaps[1-i])
+				for j := range handles {
+					handles[j].heap = i
+				}
+			},
+		)
+	})
+}
+
+func FuzzTopK(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte, k byte, split byte) {
+		k = k % 16
+		t.Logf("k = %d", k)
+		expected := xsort.MinK(xsort.OrderedLess[byte], iterator.Slice(b), int(k))
+
+		all := NewTopK(xsort.OrderedLess[byte], int(k))
+		all.AddAll(iterator.Slice(b))
+		require2.Equal(t, len(expected), all.Len())
+		require2.SlicesEqual(t, expected, all.Sorted())
+
+		// Collect two halves separately and merge them.
+		idx := 0
+		if len(b) > 0 {
+			idx = int(split) % len(b)
+		}
+		left := NewTopK(xsort.OrderedLess[byte], int(k))
+		right := NewTopK(xsort.OrderedLess[byte], int(k))
+		for _, x := range b[:idx] {
+			left.Add(x)
+		}
+		for _, x := range b[idx:] {
+			right.Add(x)
+		}
+		left.Merge(right)
+		require2.SlicesEqual(t, expected, left.Sorted())
+
+		left.Merge(left)
+		require2.SlicesEqual(t, expected, left.Sorted())
+	})
+}
+
+func FuzzStablePriorityQueue(f *testing.F) {
+	type entry struct {
+		k   int
+		p   byte
+		seq int
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		h := NewStablePriorityQueue[int](xsort.OrderedLess[byte], nil)
+		var oracle []entry
+		seq := 0
+
+		sorted := func() []int {
+			s := append([]entry{}, oracle...)
+			xsort.Slice(s, func(a, b entry) bool {
+				if a.p != b.p {
+					return a.p < b.p
+				}
+				return a.seq < b.seq
+			})
+			return xslices.Map(s, func(e entry) int { return e.k })
+		}
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				require2.Equal(t, len(oracle), h.Len())
+				require2.SlicesEqual(t, sorted(), iterator.Collect(h.IterateInOrder()))
+			},
+			func(k byte, p byte) {
+				k = k % 16
+				p = p % 4
+				t.Logf("Update(%d, %d)", k, p)
+				h.Update(int(k), p)
+				oracle = xslices.Filter(oracle, func(e entry) bool { return e.k != int(k) })
+				oracle = append(oracle, entry{k: int(k), p: p, seq: seq})
+				seq++
+			},
+			func() {
+				if len(oracle) == 0 {
+					return
+				}
+				t.Logf("Pop()")
+				expected := sorted()[0]
+				require2.Equal(t, expected, h.Peek())
+				require2.Equal(t, expected, h.Pop())
+				oracle = xslices.Filter(oracle, func(e entry) bool { return e.k != expected })
+			},
+			func(k byte) {
+				k = k % 16
+				t.Logf("Remove(%d)", k)
+				h.Remove(int(k))
+				oracle = xslices.Filter(oracle, func(e entry) bool { return e.k != int(k) })
+			},
+		)
+	})
+}
+
+func TestPriorityQueueIterateInOrder(t *testing.T) {
+	h := NewPriorityQueue(xsort.OrderedLess[int], []KP[string, int]{
+		{"e", 5},
+		{"b", 2},
+		{"d", 4},
+		{"a", 1},
+		{"c", 3},
+	})
+	require2.SlicesEqual(t, []string{"a", "b", "c", "d", "e"}, iterator.Collect(h.IterateInOrder()))
+	require2.Equal(t, 5, h.Len())
+}
+
+func TestStablePriorityQueueTies(t *testing.T) {
+	h := NewStablePriorityQueue(xsort.OrderedLess[int], []KP[string, int]{
+		{"a", 1},
+		{"b", 0},
+		{"c", 1},
+	})
+	h.Update("d", 1)
+	h.Update("e", 0)
+	h.Update("f", 1)
+	// Updating an item's priority puts it behind the others with the same priority, even if the
+	// priority didn't change.
+	h.Update("a", 1)
+
+	var popped []string
+	for h.Len() > 0 {
+		popped = append(popped, h.Pop())
+	}
+	require2.SlicesEqual(t, []string{"b", "e", "c", "d", "f", "a"}, popped)
+}
@@ -1,15 +1,32 @@
 // Package xlist contains extensions to the standard library package container/list.
 package xlist
 
+import (
+	"github.com/bradenaw/juniper/iterator"
+)
+
 // List is a doubly-linked list.
 type List[T any] struct {
 	front *Node[T]
 	back  *Node[T]
-	size  int
+	// The number of nodes in the list, or -1 if SpliceBefore has moved nodes into or out of the list
+	// since it was last counted.
+	size int
 }
 
 // Len returns the number of items in the list.
-func (l *List[T]) Len() int { return l.size }
+//
+// Len takes O(1) time, except after SpliceBefore has moved nodes between two lists, when the next
+// call to Len for each of them takes O(n) time to count its nodes.
+func (l *List[T]) Len() int {
+	if l.size < 0 {
+		l.size = 0
+		for node := l.front; node != nil; node = node.next {
+			l.grow(1)
+		}
+	}
+	return l.size
+}
 
 // Front returns the node at the front of the list.
 func (l *List[T]) Front() *Node[T] { return l.front }
@@ -33,7 +50,7 @@ func (l *List[T]) PushFront(value T) *Node[T] {
 	if l.back == nil {
 		l.back = node
 	}
-	l.size++
+	l.grow(1)
 	return node
 }
 
@@ -50,7 +67,7 @@ func (l *List[T]) PushBack(value T) *Node[T] {
 	if l.front == nil {
 		l.front = node
 	}
-	l.size++
+	l.grow(1)
 	return node
 }
 
@@ -68,7 +85,7 @@ func (l *List[T]) InsertBefore(value T, mark *Node[T]) *Node[T] {
 	if l.front == mark {
 		l.front = node
 	}
-	l.size++
+	l.grow(1)
 	return node
 }
 
@@ -86,7 +103,7 @@ func (l *List[T]) InsertAfter(value T, mark *Node[T]) *Node[T] {
 	if l.back == mark {
 		l.back = node
 	}
-	l.size++
+	l.grow(1)
 	return node
 }
 
@@ -95,7 +112,7 @@ func (l *List[T]) Remove(node *Node[T]) {
 	l.remove(node)
 	node.prev = nil
 	node.next = nil
-	l.size--
+	l.grow(-1)
 }
 
 func (l *List[T]) remove(node *Node[T]) {
@@ -155,6 +172,190 @@ func (l *List[T]) MoveToBack(node *Node[T]) {
 	l.MoveAfter(node, l.Back())
 }
 
+// PushBackList moves all of the nodes of other to the back of l in O(1) time, leaving other empty.
+func (l *List[T]) PushBackList(other *List[T]) {
+	if other == l || other.front == nil {
+		return
+	}
+	l.spliceBefore(nil, other, other.front, other.back, other.size)
+}
+
+// PushFrontList moves all of the nodes of other to the front of l in O(1) time, leaving other
+// empty.
+func (l *List[T]) PushFrontList(other *List[T]) {
+	if other == l || other.front == nil {
+		return
+	}
+	l.spliceBefore(l.front, other, other.front, other.back, other.size)
+}
+
+// SpliceBefore moves the run of nodes from first to last inclusive out of other and into l just
+// before mark, or at the back of l if mark is nil, in O(1) time. No nodes are allocated, so
+// existing references to the moved nodes remain valid.
+//
+// first must not come after last in other. other may be l, in which case mark must not be in the
+// run being moved.
+//
+// The length of the run isn't known without walking it, so if other is not l, the next call to Len
+// for each of the two lists counts its nodes.
+func (l *List[T]) SpliceBefore(mark *Node[T], other *List[T], first *Node[T], last *Node[T]) {
+	l.spliceBefore(mark, other, first, last, -1)
+}
+
+// MoveToList moves node from l to the back of other in O(1) time. No node is allocated, so
+// existing references to node remain valid.
+func (l *List[T]) MoveToList(node *Node[T], other *List[T]) {
+	other.spliceBefore(nil, l, node, node, 1)
+}
+
+// spliceBefore is SpliceBefore for a run of n nodes, or of unknown length if n is -1.
+func (l *List[T]) spliceBefore(
+	mark *Node[T],
+	other *List[T],
+	first *Node[T],
+	last *Node[T],
+	n int,
+) {
+	if mark == first {
+		return
+	}
+	other.unlinkRun(first, last)
+	l.linkRunBefore(first, last, mark)
+	if other == l {
+		return
+	}
+	if n < 0 {
+		other.size = -1
+		l.size = -1
+	} else {
+		other.grow(-n)
+		l.grow(n)
+	}
+}
+
+// grow adds delta to l's size, unless it needs to be counted anyway.
+func (l *List[T]) grow(delta int) {
+	if l.size >= 0 {
+		l.size += delta
+	}
+}
+
+// Split removes at and all of the nodes after it from l and returns them as a new list.
+//
+// Split takes O(min(k, n-k)) time, where k is the number of nodes moved to the new list and n is
+// the original length of l.
+func (l *List[T]) Split(at *Node[T]) *List[T] {
+	n := l.Len()
+	// Count whichever side is shorter by walking outward from at in both directions at once.
+	after := 0
+	before := 0
+	forward := at
+	backward := at.prev
+	for {
+		if forward == nil {
+			before = n - after
+			break
+		}
+		if backward == nil {
+			after = n - before
+			break
+		}
+		forward = forward.next
+		after++
+		backward = backward.prev
+		before++
+	}
+
+	other := &List[T]{
+		front: at,
+		back:  l.back,
+		size:  after,
+	}
+	l.back = at.prev
+	if l.back == nil {
+		l.front = nil
+	} else {
+		l.back.next = nil
+	}
+	at.prev = nil
+	l.size = before
+	return other
+}
+
+// Iterate iterates over the values of the list from front to back.
+//
+// It is safe to remove the node of the most-recently yielded value during iteration. The iterator
+// is otherwise invalidated if the list is modified.
+func (l *List[T]) Iterate() iterator.Iterator[T] {
+	return &listIterator[T]{next: l.front, forward: true}
+}
+
+// Backward iterates over the values of the list from back to front.
+//
+// It is safe to remove the node of the most-recently yielded value during iteration. The iterator
+// is otherwise invalidated if the list is modified.
+func (l *List[T]) Backward() iterator.Iterator[T] {
+	return &listIterator[T]{next: l.back, forward: false}
+}
+
+type listIterator[T any] struct {
+	next    *Node[T]
+	forward bool
+}
+
+func (iter *listIterator[T]) Next() (T, bool) {
+	if iter.next == nil {
+		var zero T
+		return zero, false
+	}
+	node := iter.next
+	if iter.forward {
+		iter.next = node.next
+	} else {
+		iter.next = node.prev
+	}
+	return node.Value, true
+}
+
+// unlinkRun detaches the nodes from first to last inclusive from l, without changing l.size.
+func (l *List[T]) unlinkRun(first *Node[T], last *Node[T]) {
+	if first.prev != nil {
+		first.prev.next = last.next
+	} else {
+		l.front = last.next
+	}
+	if last.next != nil {
+		last.next.prev = first.prev
+	} else {
+		l.back = first.prev
+	}
+	first.prev = nil
+	last.next = nil
+}
+
+// linkRunBefore links the detached run of nodes from first to last inclusive into l just before
+// mark, or at the back if mark is nil, without changing l.size.
+func (l *List[T]) linkRunBefore(first *Node[T], last *Node[T], mark *Node[T]) {
+	if mark == nil {
+		first.prev = l.back
+		if l.back != nil {
+			l.back.next = first
+		} else {
+			l.front = first
+		}
+		l.back = last
+		return
+	}
+	first.prev = mark.prev
+	last.next = mark
+	if mark.prev != nil {
+		mark.prev.next = first
+	} else {
+		l.front = first
+	}
+	mark.prev = last
+}
+
 // Node is a node in a linked-list.
 type Node[T any] struct {
 	prev *Node[T]
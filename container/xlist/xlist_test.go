@@ -5,6 +5,7 @@ import (
 
 	"github.com/bradenaw/juniper/internal/fuzz"
 	"github.com/bradenaw/juniper/internal/require2"
+	"github.com/bradenaw/juniper/iterator"
 	"github.com/bradenaw/juniper/xslices"
 )
 
@@ -140,3 +141,114 @@ func FuzzList(f *testing.F) {
 		)
 	})
 }
+
+func FuzzListSplice(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte) {
+		lists := [2]*List[int]{{}, {}}
+		var oracles [2][]int
+
+		nodeAt := func(l *List[int], i int) *Node[int] {
+			curr := l.Front()
+			for j := 0; j < i; j++ {
+				curr = curr.Next()
+			}
+			return curr
+		}
+		pick := func(which bool) int {
+			if which {
+				return 1
+			}
+			return 0
+		}
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				for i := range lists {
+					require2.Equal(t, len(oracles[i]), lists[i].Len())
+					require2.SlicesEqual(t, oracles[i], iterator.Collect(lists[i].Iterate()))
+					backward := iterator.Collect(lists[i].Backward())
+					xslices.Reverse(backward)
+					require2.SlicesEqual(t, oracles[i], backward)
+				}
+			},
+			func(which bool, value byte) {
+				i := pick(which)
+				t.Logf("lists[%d].PushBack(%d)", i, value)
+				lists[i].PushBack(int(value))
+				oracles[i] = append(oracles[i], int(value))
+			},
+			func(which bool) {
+				i := pick(which)
+				t.Logf("lists[%d].PushBackList(lists[%d])", i, 1-i)
+				lists[i].PushBackList(lists[1-i])
+				oracles[i] = append(oracles[i], oracles[1-i]...)
+				oracles[1-i] = nil
+			},
+			func(which bool) {
+				i := pick(which)
+				t.Logf("lists[%d].PushFrontList(lists[%d])", i, 1-i)
+				lists[i].PushFrontList(lists[1-i])
+				oracles[i] = append(append([]int{}, oracles[1-i]...), oracles[i]...)
+				oracles[1-i] = nil
+			},
+			func(which bool, idx int) {
+				i := pick(which)
+				if len(oracles[i]) == 0 || idx < 0 {
+					return
+				}
+				idx = idx % len(oracles[i])
+				t.Logf("lists[%d].MoveToList(node @ %d, lists[%d])", i, idx, 1-i)
+				lists[i].MoveToList(nodeAt(lists[i], idx), lists[1-i])
+				oracles[1-i] = append(oracles[1-i], oracles[i][idx])
+				oracles[i] = xslices.Remove(oracles[i], idx, 1)
+			},
+			func(to bool, from bool, markIdx int, firstIdx int, n int) {
+				i := pick(to)
+				j := pick(from)
+				if len(oracles[j]) == 0 || markIdx < 0 || firstIdx < 0 || n < 0 {
+					return
+				}
+				firstIdx = firstIdx % len(oracles[j])
+				lastIdx := firstIdx + n%(len(oracles[j])-firstIdx)
+				// len(oracles[i]) means push to the back.
+				markIdx = markIdx % (len(oracles[i]) + 1)
+				if i == j && markIdx >= firstIdx && markIdx <= lastIdx {
+					return
+				}
+				var mark *Node[int]
+				if markIdx < len(oracles[i]) {
+					mark = nodeAt(lists[i], markIdx)
+				}
+				t.Logf(
+					"lists[%d].SpliceBefore(node @ %d, lists[%d], node @ %d, node @ %d)",
+					i, markIdx, j, firstIdx, lastIdx,
+				)
+				lists[i].SpliceBefore(
+					mark,
+					lists[j],
+					nodeAt(lists[j], firstIdx),
+					nodeAt(lists[j], lastIdx),
+				)
+
+				run := append([]int{}, oracles[j][firstIdx:lastIdx+1]...)
+				if i == j && markIdx > lastIdx {
+					markIdx -= len(run)
+				}
+				oracles[j] = xslices.Remove(oracles[j], firstIdx, len(run))
+				oracles[i] = xslices.Insert(oracles[i], markIdx, run...)
+			},
+			func(which bool, idx int) {
+				i := pick(which)
+				if len(oracles[i]) == 0 || idx < 0 {
+					return
+				}
+				idx = idx % len(oracles[i])
+				t.Logf("lists[%d] = lists[%d].Split(node @ %d)", 1-i, i, idx)
+				lists[1-i] = lists[i].Split(nodeAt(lists[i], idx))
+				oracles[1-i] = append([]int{}, oracles[i][idx:]...)
+				oracles[i] = oracles[i][:idx]
+			},
+		)
+	})
+}
@@ -0,0 +1,294 @@
+// Package graph contains algorithms for directed graphs, like topological sorting, strongly
+// connected components, traversals, and shortest paths.
+//
+// Algorithms work on any type that can list the neighbors of a vertex, so graphs can be computed
+// lazily or backed by whatever structure is convenient. AdjacencyList is provided for graphs that
+// are simplest to build up front.
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bradenaw/juniper/container/deque"
+	"github.com/bradenaw/juniper/iterator"
+)
+
+// Graph is a directed graph with vertices of type V.
+type Graph[V comparable] interface {
+	// Neighbors returns the vertices that have an edge from v to them.
+	Neighbors(v V) iterator.Iterator[V]
+}
+
+// GraphFunc is a Graph whose Neighbors method calls the function itself.
+type GraphFunc[V comparable] func(v V) iterator.Iterator[V]
+
+// Neighbors returns f(v).
+func (f GraphFunc[V]) Neighbors(v V) iterator.Iterator[V] {
+	return f(v)
+}
+
+// AdjacencyList is a Graph that maps each vertex to its neighbors.
+type AdjacencyList[V comparable] map[V][]V
+
+// Neighbors returns the neighbors of v.
+func (g AdjacencyList[V]) Neighbors(v V) iterator.Iterator[V] {
+	return iterator.Slice(g[v])
+}
+
+// AddEdge adds an edge from `from` to `to`.
+func (g AdjacencyList[V]) AddEdge(from V, to V) {
+	g[from] = append(g[from], to)
+}
+
+// Vertices returns every vertex that has an edge to or from it in no particular order.
+func (g AdjacencyList[V]) Vertices() []V {
+	seen := make(map[V]struct{}, len(g))
+	var out []V
+	add := func(v V) {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			out = append(out, v)
+		}
+	}
+	for v, neighbors := range g {
+		add(v)
+		for _, w := range neighbors {
+			add(w)
+		}
+	}
+	return out
+}
+
+// BFS returns an iterator that yields the vertices reachable from start in breadth-first order,
+// beginning with the start vertices themselves. Each vertex is yielded once.
+func BFS[V comparable](g Graph[V], start ...V) iterator.Iterator[V] {
+	iter := &bfsIterator[V]{g: g, visited: make(map[V]struct{})}
+	for _, v := range start {
+		iter.visit(v)
+	}
+	return iter
+}
+
+type bfsIterator[V comparable] struct {
+	g       Graph[V]
+	visited map[V]struct{}
+	queue   deque.Deque[V]
+}
+
+func (iter *bfsIterator[V]) visit(v V) {
+	if _, ok := iter.visited[v]; ok {
+		return
+	}
+	iter.visited[v] = struct{}{}
+	iter.queue.PushBack(v)
+}
+
+func (iter *bfsIterator[V]) Next() (V, bool) {
+	if iter.queue.Len() == 0 {
+		var zero V
+		return zero, false
+	}
+	v := iter.queue.PopFront()
+	neighbors := iter.g.Neighbors(v)
+	for {
+		w, ok := neighbors.Next()
+		if !ok {
+			break
+		}
+		iter.visit(w)
+	}
+	return v, true
+}
+
+// DFS returns an iterator that yields the vertices reachable from start in depth-first preorder.
+// Each vertex is yielded once.
+//
+// The iterator is lazy, so it only calls g.Neighbors as much as is needed to produce each vertex.
+func DFS[V comparable](g Graph[V], start ...V) iterator.Iterator[V] {
+	return &dfsIterator[V]{g: g, start: start, visited: make(map[V]struct{})}
+}
+
+type dfsIterator[V comparable] struct {
+	g       Graph[V]
+	start   []V
+	visited map[V]struct{}
+	stack   []iterator.Iterator[V]
+}
+
+func (iter *dfsIterator[V]) Next() (V, bool) {
+	for {
+		var v V
+		if len(iter.stack) == 0 {
+			if len(iter.start) == 0 {
+				var zero V
+				return zero, false
+			}
+			v = iter.start[0]
+			iter.start = iter.start[1:]
+		} else {
+			var ok bool
+			v, ok = iter.stack[len(iter.stack)-1].Next()
+			if !ok {
+				iter.stack = iter.stack[:len(iter.stack)-1]
+				continue
+			}
+		}
+		if _, ok := iter.visited[v]; ok {
+			continue
+		}
+		iter.visited[v] = struct{}{}
+		iter.stack = append(iter.stack, iter.g.Neighbors(v))
+		return v, true
+	}
+}
+
+// CycleError is returned by TopoSort when the graph has a cycle.
+type CycleError[V any] struct {
+	// The vertices of one of the cycles, in order. The last vertex has an edge to the first.
+	Cycle []V
+}
+
+func (err *CycleError[V]) Error() string {
+	var sb strings.Builder
+	sb.WriteString("graph has a cycle: ")
+	for _, v := range err.Cycle {
+		fmt.Fprintf(&sb, "%v -> ", v)
+	}
+	if len(err.Cycle) > 0 {
+		fmt.Fprintf(&sb, "%v", err.Cycle[0])
+	}
+	return sb.String()
+}
+
+type visitState int
+
+const (
+	unvisited visitState = iota
+	inProgress
+	done
+)
+
+type dfsFrame[V any] struct {
+	v         V
+	neighbors iterator.Iterator[V]
+}
+
+// TopoSort returns the vertices in vertices and all vertices reachable from them, ordered so that
+// for every edge from u to v, u comes before v.
+//
+// If the graph has a cycle, TopoSort returns a *CycleError describing one of them.
+//
+// For example, if an edge from a to b means that a must be built before b, TopoSort returns a valid
+// order in which to build everything. If instead an edge means that a depends on b, reverse the
+// result.
+func TopoSort[V comparable](g Graph[V], vertices []V) ([]V, error) {
+	state := make(map[V]visitState)
+	var postorder []V
+	var stack []dfsFrame[V]
+	for _, root := range vertices {
+		if state[root] != unvisited {
+			continue
+		}
+		state[root] = inProgress
+		stack = append(stack, dfsFrame[V]{root, g.Neighbors(root)})
+		for len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			w, ok := top.neighbors.Next()
+			if !ok {
+				state[top.v] = done
+				postorder = append(postorder, top.v)
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			switch state[w] {
+			case unvisited:
+				state[w] = inProgress
+				stack = append(stack, dfsFrame[V]{w, g.Neighbors(w)})
+			case inProgress:
+				// w is on the stack, so the stack from w to here is a cycle.
+				i := len(stack) - 1
+				for stack[i].v != w {
+					i--
+				}
+				cycle := make([]V, 0, len(stack)-i)
+				for _, frame := range stack[i:] {
+					cycle = append(cycle, frame.v)
+				}
+				return nil, &CycleError[V]{Cycle: cycle}
+			}
+		}
+	}
+	for i, j := 0, len(postorder)-1; i < j; i, j = i+1, j-1 {
+		postorder[i], postorder[j] = postorder[j], postorder[i]
+	}
+	return postorder, nil
+}
+
+// StronglyConnectedComponents returns the strongly connected components of the subgraph made of
+// vertices and all vertices reachable from them. Two vertices are in the same strongly connected
+// component if each can be reached from the other.
+//
+// Components are returned in reverse topological order: if there is an edge from a vertex in one
+// component to a vertex in another, the second component comes first. Every vertex is in exactly
+// one component, so a graph without cycles has one component per vertex.
+func StronglyConnectedComponents[V comparable](g Graph[V], vertices []V) [][]V {
+	// Tarjan's algorithm, iteratively.
+	type info struct {
+		index   int
+		low     int
+		onStack bool
+	}
+	infos := make(map[V]*info)
+	var components [][]V
+	var stack []V
+	var frames []dfsFrame[V]
+	visit := func(v V) {
+		infos[v] = &info{index: len(infos), low: len(infos), onStack: true}
+		stack = append(stack, v)
+		frames = append(frames, dfsFrame[V]{v, g.Neighbors(v)})
+	}
+
+	for _, root := range vertices {
+		if _, ok := infos[root]; ok {
+			continue
+		}
+		visit(root)
+		for len(frames) > 0 {
+			top := frames[len(frames)-1]
+			vInfo := infos[top.v]
+			w, ok := top.neighbors.Next()
+			if ok {
+				wInfo, visited := infos[w]
+				if !visited {
+					visit(w)
+				} else if wInfo.onStack && wInfo.index < vInfo.low {
+					vInfo.low = wInfo.index
+				}
+				continue
+			}
+
+			frames = frames[:len(frames)-1]
+			if vInfo.low == vInfo.index {
+				// top.v is the root of a component, which is everything above it on the stack.
+				i := len(stack) - 1
+				for stack[i] != top.v {
+					i--
+				}
+				component := append([]V(nil), stack[i:]...)
+				for _, w := range component {
+					infos[w].onStack = false
+				}
+				stack = stack[:i]
+				components = append(components, component)
+			}
+			if len(frames) > 0 {
+				parentInfo := infos[frames[len(frames)-1].v]
+				if vInfo.low < parentInfo.low {
+					parentInfo.low = vInfo.low
+				}
+			}
+		}
+	}
+	return components
+}
@@ -0,0 +1,241 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/bradenaw/juniper/internal/require2"
+	"github.com/bradenaw/juniper/iterator"
+	"github.com/bradenaw/juniper/xslices"
+)
+
+func randomGraph(r *rand.Rand, n int, edges int) AdjacencyList[int] {
+	g := make(AdjacencyList[int])
+	for i := 0; i < edges; i++ {
+		g.AddEdge(r.Intn(n), r.Intn(n))
+	}
+	return g
+}
+
+// reachable returns whether there's a path from a to b, by brute force.
+func reachable(g AdjacencyList[int], a, b int) bool {
+	return xslices.Index(iterator.Collect(BFS[int](g, a)), b) != -1
+}
+
+func TestBFSAndDFS(t *testing.T) {
+	g := AdjacencyList[string]{
+		"a": {"b", "c"},
+		"b": {"d"},
+		"c": {"d", "e"},
+		"d": {"a"},
+	}
+	require2.SlicesEqual(t, []string{"a", "b", "c", "d", "e"}, iterator.Collect(BFS[string](g, "a")))
+	require2.SlicesEqual(t, []string{"a", "b", "d", "c", "e"}, iterator.Collect(DFS[string](g, "a")))
+	require2.SlicesEqual(t, []string{"c", "d", "a", "b", "e"}, iterator.Collect(DFS[string](g, "c")))
+	require2.SlicesEqual(t, []string{"e", "b", "d", "a", "c"}, iterator.Collect(BFS[string](g, "e", "b")))
+}
+
+func TestTopoSort(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 1000; i++ {
+		n := r.Intn(20) + 1
+		g := randomGraph(r, n, r.Intn(2*n))
+		vertices := iterator.Collect(iterator.Counter(n))
+
+		order, err := TopoSort[int](g, vertices)
+		var cycleErr *CycleError[int]
+		if errors.As(err, &cycleErr) {
+			cycle := cycleErr.Cycle
+			require2.Greater(t, len(cycle), 0)
+			for j := range cycle {
+				next := cycle[(j+1)%len(cycle)]
+				require2.True(t, xslices.Index(g[cycle[j]], next) != -1)
+			}
+			continue
+		}
+		require2.NoError(t, err)
+		require2.Equal(t, n, len(order))
+		position := make(map[int]int)
+		for j, v := range order {
+			position[v] = j
+		}
+		for from, tos := range g {
+			for _, to := range tos {
+				require2.Less(t, position[from], position[to])
+			}
+		}
+	}
+}
+
+func TestCycleError(t *testing.T) {
+	g := AdjacencyList[string]{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"a"},
+	}
+	_, err := TopoSort[string](g, []string{"a"})
+	require2.Equal(t, "graph has a cycle: a -> b -> c -> a", err.Error())
+}
+
+func TestStronglyConnectedComponents(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 1000; i++ {
+		n := r.Intn(20) + 1
+		g := randomGraph(r, n, r.Intn(2*n))
+		vertices := iterator.Collect(iterator.Counter(n))
+
+		components := StronglyConnectedComponents[int](g, vertices)
+		componentOf := make(map[int]int)
+		for j, component := range components {
+			for _, v := range component {
+				_, ok := componentOf[v]
+				require2.True(t, !ok)
+				componentOf[v] = j
+			}
+		}
+		require2.Equal(t, n, len(componentOf))
+		for a := 0; a < n; a++ {
+			for b := 0; b < n; b++ {
+				sameComponent := reachable(g, a, b) && reachable(g, b, a)
+				require2.Equal(t, sameComponent, componentOf[a] == componentOf[b])
+				// Reverse topological order.
+				if reachable(g, a, b) {
+					require2.GreaterOrEqual(t, componentOf[a], componentOf[b])
+				}
+			}
+		}
+	}
+}
+
+func TestShortestPaths(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 200; i++ {
+		n := r.Intn(15) + 1
+		g := randomGraph(r, n, r.Intn(3*n))
+		weights := make(map[[2]int]float64)
+		for from, tos := range g {
+			for _, to := range tos {
+				weights[[2]int{from, to}] = float64(r.Intn(10))
+			}
+		}
+		weight := func(from, to int) float64 { return weights[[2]int{from, to}] }
+
+		// Floyd-Warshall as the oracle.
+		dist := make([][]float64, n)
+		for a := range dist {
+			dist[a] = make([]float64, n)
+			for b := range dist[a] {
+				dist[a][b] = math.Inf(1)
+			}
+			dist[a][a] = 0
+		}
+		for e, w := range weights {
+			dist[e[0]][e[1]] = math.Min(dist[e[0]][e[1]], w)
+		}
+		for k := 0; k < n; k++ {
+			for a := 0; a < n; a++ {
+				for b := 0; b < n; b++ {
+					dist[a][b] = math.Min(dist[a][b], dist[a][k]+dist[k][b])
+				}
+			}
+		}
+
+		pathWeight := func(path []int) float64 {
+			total := 0.0
+			for j := 1; j < len(path); j++ {
+				require2.True(t, xslices.Index(g[path[j-1]], path[j]) != -1)
+				total += weight(path[j-1], path[j])
+			}
+			return total
+		}
+
+		for a := 0; a < n; a++ {
+			paths := ShortestPaths[int](g, a, weight)
+			for b := 0; b < n; b++ {
+				d, ok := paths.Distance(b)
+				require2.Equal(t, !math.IsInf(dist[a][b], 1), ok)
+				path, d2, ok2 := ShortestPath[int](g, a, b, weight)
+				require2.Equal(t, ok, ok2)
+				if !ok {
+					require2.True(t, paths.PathTo(b) == nil)
+					continue
+				}
+				require2.Equal(t, dist[a][b], d)
+				require2.Equal(t, dist[a][b], d2)
+				require2.Equal(t, a, path[0])
+				require2.Equal(t, b, path[len(path)-1])
+				require2.Equal(t, d, pathWeight(paths.PathTo(b)))
+				require2.Equal(t, d, pathWeight(path))
+			}
+		}
+	}
+}
+
+func TestAStar(t *testing.T) {
+	// A grid with some walls.
+	type point struct{ x, y int }
+	const size = 20
+	walls := make(map[point]bool)
+	for y := 0; y < size-2; y++ {
+		walls[point{10, y}] = true
+	}
+	expanded := 0
+	g := GraphFunc[point](func(p point) iterator.Iterator[point] {
+		expanded++
+		var out []point
+		for _, d := range []point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			q := point{p.x + d.x, p.y + d.y}
+			if q.x >= 0 && q.x < size && q.y >= 0 && q.y < size && !walls[q] {
+				out = append(out, q)
+			}
+		}
+		return iterator.Slice(out)
+	})
+	weight := func(from, to point) float64 { return 1 }
+	target := point{size - 1, 0}
+	manhattan := func(p point) float64 {
+		return math.Abs(float64(target.x-p.x)) + math.Abs(float64(target.y-p.y))
+	}
+
+	path, d, ok := AStar[point](g, point{0, 0}, target, weight, manhattan)
+	require2.True(t, ok)
+	aStarExpanded := expanded
+	// Around the wall: down to y=18, across, and back up.
+	require2.Equal(t, float64(18+19+18), d)
+	require2.Equal(t, int(d)+1, len(path))
+
+	expanded = 0
+	_, d2, ok := ShortestPath[point](g, point{0, 0}, target, weight)
+	require2.True(t, ok)
+	require2.Equal(t, d, d2)
+	t.Logf("A* expanded %d vertices, Dijkstra expanded %d", aStarExpanded, expanded)
+	require2.Less(t, aStarExpanded, expanded)
+}
+
+func ExampleTopoSort() {
+	// An edge from a to b means a must be done before b.
+	g := AdjacencyList[string]{
+		"wake up":      {"shower", "make coffee"},
+		"make coffee":  {"drink coffee"},
+		"shower":       {"get dressed"},
+		"get dressed":  {"leave"},
+		"drink coffee": {"leave"},
+		"feed the cat": {"leave"},
+	}
+	vertices := g.Vertices()
+	sort.Strings(vertices)
+	order, err := TopoSort[string](g, vertices)
+	fmt.Println(order, err)
+
+	g.AddEdge("leave", "wake up")
+	_, err = TopoSort[string](g, vertices)
+	fmt.Println(err)
+
+	// Output:
+	// [wake up shower make coffee get dressed feed the cat drink coffee leave] <nil>
+	// graph has a cycle: leave -> wake up -> shower -> get dressed -> leave
+}
@@ -0,0 +1,136 @@
+package graph
+
+import (
+	"github.com/bradenaw/juniper/container/xheap"
+	"github.com/bradenaw/juniper/xsort"
+)
+
+// Paths holds the shortest paths from a source vertex to every vertex reachable from it.
+type Paths[V comparable] struct {
+	source V
+	dist   map[V]float64
+	prev   map[V]V
+}
+
+// Distance returns the total weight of the shortest path to v, or false in the second return if v
+// is not reachable.
+func (p *Paths[V]) Distance(v V) (float64, bool) {
+	d, ok := p.dist[v]
+	return d, ok
+}
+
+// PathTo returns the vertices of the shortest path to v, beginning with the source and ending with
+// v, or nil if v is not reachable.
+func (p *Paths[V]) PathTo(v V) []V {
+	if _, ok := p.dist[v]; !ok {
+		return nil
+	}
+	var path []V
+	for v != p.source {
+		path = append(path, v)
+		v = p.prev[v]
+	}
+	path = append(path, p.source)
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// ShortestPaths finds the shortest paths from source to every vertex reachable from it using
+// Dijkstra's algorithm. weight returns the weight of the edge from one vertex to another, and must
+// never be negative.
+//
+// ShortestPaths takes O((V + E) * log(V)) time for the V vertices and E edges reachable from
+// source.
+func ShortestPaths[V comparable](g Graph[V], source V, weight func(from, to V) float64) *Paths[V] {
+	p, _ := search(g, source, nil, weight, nil)
+	return p
+}
+
+// ShortestPath returns the shortest path from source to target, as in ShortestPaths, and its total
+// weight. Returns false in the third return if target is not reachable from source.
+//
+// ShortestPath stops searching once it has found the path to target, so it may be much faster than
+// ShortestPaths.
+func ShortestPath[V comparable](
+	g Graph[V],
+	source V,
+	target V,
+	weight func(from, to V) float64,
+) ([]V, float64, bool) {
+	return AStar(g, source, target, weight, nil)
+}
+
+// AStar returns the shortest path from source to target using the A* search algorithm, and its
+// total weight. Returns false in the third return if target is not reachable from source.
+//
+// weight returns the weight of the edge from one vertex to another, and must never be negative.
+// heuristic estimates the weight of the shortest path from a vertex to target, and guides the
+// search towards target so that it needs to look at fewer vertices. To find the shortest path,
+// heuristic must never overestimate, and following an edge must not decrease heuristic by more than
+// the edge's weight. For example, in a graph of points on a map the straight-line distance is a
+// good heuristic. A nil heuristic is the same as one that always returns 0, in which case AStar is
+// the same as Dijkstra's algorithm.
+func AStar[V comparable](
+	g Graph[V],
+	source V,
+	target V,
+	weight func(from, to V) float64,
+	heuristic func(v V) float64,
+) ([]V, float64, bool) {
+	p, ok := search(g, source, &target, weight, heuristic)
+	if !ok {
+		return nil, 0, false
+	}
+	return p.PathTo(target), p.dist[target], true
+}
+
+// search finds shortest paths from source, stopping early if target is non-nil and found.
+func search[V comparable](
+	g Graph[V],
+	source V,
+	target *V,
+	weight func(from, to V) float64,
+	heuristic func(v V) float64,
+) (*Paths[V], bool) {
+	if heuristic == nil {
+		heuristic = func(V) float64 { return 0 }
+	}
+	p := &Paths[V]{
+		source: source,
+		dist:   map[V]float64{source: 0},
+		prev:   make(map[V]V),
+	}
+	// Tentative distances of vertices that have been reached but not yet settled, prioritized by
+	// distance plus the estimate of the remaining distance.
+	frontier := xheap.NewPriorityQueue[V](xsort.OrderedLess[float64], nil)
+	frontier.Update(source, heuristic(source))
+	settled := make(map[V]struct{})
+
+	for frontier.Len() > 0 {
+		v := frontier.Pop()
+		if target != nil && v == *target {
+			return p, true
+		}
+		settled[v] = struct{}{}
+		neighbors := g.Neighbors(v)
+		for {
+			w, ok := neighbors.Next()
+			if !ok {
+				break
+			}
+			if _, ok := settled[w]; ok {
+				continue
+			}
+			d := p.dist[v] + weight(v, w)
+			if existing, ok := p.dist[w]; ok && existing <= d {
+				continue
+			}
+			p.dist[w] = d
+			p.prev[w] = v
+			frontier.Update(w, d+heuristic(w))
+		}
+	}
+	return p, target == nil
+}
@@ -0,0 +1,13 @@
+// Package hash contains helpers shared by the hash-based containers.
+package hash
+
+// Mix scrambles the bits of x so that every bit of the output depends on every bit of the input.
+// It is the finalizer of SplitMix64.
+func Mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
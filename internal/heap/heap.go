@@ -96,6 +96,7 @@ func (h *Heap[T]) UpdateAt(i int, item T) {
 	h.notifyIndexChanged(i)
 	h.percolateUp(i)
 	h.percolateDown(i)
+	h.gen++
 }
 
 func (h *Heap[T]) percolateUp(i int) {
@@ -172,6 +173,49 @@ func (h *Heap[T]) Iterate() iterator.Iterator[T] {
 	return &heapIterator[T]{h: h, gen: -1}
 }
 
+type orderedHeapIterator[T any] struct {
+	h   *Heap[T]
+	gen int
+	// Indexes into h.a of the items that could be next, which are the children of all of the items
+	// yielded so far.
+	frontier Heap[int]
+}
+
+func (iter *orderedHeapIterator[T]) Next() (T, bool) {
+	if iter.gen == -1 {
+		iter.gen = iter.h.gen
+		iter.frontier = New(
+			func(i, j int) bool { return iter.h.less(i, j) },
+			func(i int, j int) {},
+			nil,
+		)
+		if len(iter.h.a) > 0 {
+			iter.frontier.Push(0)
+		}
+	} else if iter.gen != iter.h.gen {
+		panic(ErrHeapModified)
+	}
+	if iter.frontier.Len() == 0 {
+		var zero T
+		return zero, false
+	}
+	i := iter.frontier.Pop()
+	left, right := children(i)
+	if left < len(iter.h.a) {
+		iter.frontier.Push(left)
+	}
+	if right < len(iter.h.a) {
+		iter.frontier.Push(right)
+	}
+	return iter.h.a[i], true
+}
+
+// IterateOrdered iterates over the items of the heap in the same order that repeated calls to Pop
+// would yield them, without modifying the heap.
+func (h *Heap[T]) IterateOrdered() iterator.Iterator[T] {
+	return &orderedHeapIterator[T]{h: h, gen: -1}
+}
+
 func parent(i int) int {
 	return (i - 1) / 2
 }
@@ -9,6 +9,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/bradenaw/juniper/container/xheap"
 	"github.com/bradenaw/juniper/iterator"
 	"github.com/bradenaw/juniper/xmath"
 )
@@ -340,6 +341,25 @@ func Last[T any](ctx context.Context, s Stream[T], n int) ([]T, error) {
 	return out, nil
 }
 
+// MinK consumes s and returns the k minimum items according to less in sorted order. If s yields
+// fewer than k items, MinK returns all of them. To find the k maximum items instead, reverse less.
+//
+// MinK uses O(k) space regardless of how many items s yields.
+func MinK[T any](ctx context.Context, s Stream[T], less func(a, b T) bool, k int) ([]T, error) {
+	defer s.Close()
+
+	topK := xheap.NewTopK(less, k)
+	for {
+		item, err := s.Next(ctx)
+		if err == End {
+			return topK.Sorted(), nil
+		} else if err != nil {
+			return nil, err
+		}
+		topK.Add(item)
+	}
+}
+
 // One returns the only item that s yields. Returns an error if encountered, or if s yields zero or
 // more than one item.
 func One[T any](ctx context.Context, s Stream[T]) (T, error) {
@@ -264,6 +264,17 @@ func ExampleMerge() {
 	// y
 }
 
+func ExampleMinK() {
+	ctx := context.Background()
+	s := stream.FromIterator(iterator.Slice([]int{7, 4, 3, 8, 2, 1, 6, 9, 0, 5}))
+
+	min3, err := stream.MinK(ctx, s, func(a, b int) bool { return a < b }, 3)
+	fmt.Println(min3, err)
+
+	// Output:
+	// [0 1 2] <nil>
+}
+
 func ExampleOne() {
 	ctx := context.Background()
 
@@ -0,0 +1,156 @@
+package xmaps
+
+import (
+	"sync"
+
+	"github.com/bradenaw/juniper/iterator"
+)
+
+// BiMap is a one-to-one mapping between keys and values, which can be looked up in either
+// direction in O(1) time. Every key maps to exactly one value and every value maps back to exactly
+// one key.
+//
+// Unlike keeping a map and the result of ReverseSingle, the two directions are always updated
+// together and so can never disagree.
+//
+// BiMap is safe for concurrent use by multiple goroutines. Each method is atomic, so readers never
+// see one direction updated without the other.
+type BiMap[K comparable, V comparable] struct {
+	// Shared with the BiMap returned by Inverse.
+	m        *sync.RWMutex
+	forward  map[K]V
+	backward map[V]K
+}
+
+// NewBiMap returns an empty BiMap.
+func NewBiMap[K comparable, V comparable]() *BiMap[K, V] {
+	return &BiMap[K, V]{
+		m:        &sync.RWMutex{},
+		forward:  make(map[K]V),
+		backward: make(map[V]K),
+	}
+}
+
+// Len returns the number of key-value pairs in m.
+func (m *BiMap[K, V]) Len() int {
+	m.m.RLock()
+	defer m.m.RUnlock()
+	return len(m.forward)
+}
+
+// Get returns the value associated with k, or false in the second return if k is not in m.
+func (m *BiMap[K, V]) Get(k K) (V, bool) {
+	m.m.RLock()
+	defer m.m.RUnlock()
+	v, ok := m.forward[k]
+	return v, ok
+}
+
+// GetKey returns the key associated with v, or false in the second return if v is not in m.
+func (m *BiMap[K, V]) GetKey(v V) (K, bool) {
+	m.m.RLock()
+	defer m.m.RUnlock()
+	k, ok := m.backward[v]
+	return k, ok
+}
+
+// ContainsKey returns true if k is in m.
+func (m *BiMap[K, V]) ContainsKey(k K) bool {
+	m.m.RLock()
+	defer m.m.RUnlock()
+	_, ok := m.forward[k]
+	return ok
+}
+
+// ContainsValue returns true if v is in m.
+func (m *BiMap[K, V]) ContainsValue(v V) bool {
+	m.m.RLock()
+	defer m.m.RUnlock()
+	_, ok := m.backward[v]
+	return ok
+}
+
+// Put associates k with v. Any existing pairs with key k or with value v are removed first, so Put
+// may shrink m by one.
+func (m *BiMap[K, V]) Put(k K, v V) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.deleteKeyLocked(k)
+	m.deleteValueLocked(v)
+	m.forward[k] = v
+	m.backward[v] = k
+}
+
+// TryPut associates k with v only if neither k nor v is already in m, and returns whether it did.
+// TryPut returns true without changing m if k is already associated with v.
+func (m *BiMap[K, V]) TryPut(k K, v V) bool {
+	m.m.Lock()
+	defer m.m.Unlock()
+	existing, ok := m.forward[k]
+	if ok {
+		return existing == v
+	}
+	if _, ok := m.backward[v]; ok {
+		return false
+	}
+	m.forward[k] = v
+	m.backward[v] = k
+	return true
+}
+
+// DeleteKey removes k and its associated value from m, if present.
+func (m *BiMap[K, V]) DeleteKey(k K) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.deleteKeyLocked(k)
+}
+
+func (m *BiMap[K, V]) deleteKeyLocked(k K) {
+	v, ok := m.forward[k]
+	if !ok {
+		return
+	}
+	delete(m.forward, k)
+	delete(m.backward, v)
+}
+
+// DeleteValue removes v and its associated key from m, if present.
+func (m *BiMap[K, V]) DeleteValue(v V) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.deleteValueLocked(v)
+}
+
+func (m *BiMap[K, V]) deleteValueLocked(v V) {
+	k, ok := m.backward[v]
+	if !ok {
+		return
+	}
+	delete(m.forward, k)
+	delete(m.backward, v)
+}
+
+// Inverse returns a view of m with keys and values swapped. The view shares m's contents, so
+// changes made through either are visible in both.
+func (m *BiMap[K, V]) Inverse() *BiMap[V, K] {
+	return &BiMap[V, K]{
+		m:        m.m,
+		forward:  m.backward,
+		backward: m.forward,
+	}
+}
+
+// Keys returns an iterator over the keys of m in arbitrary order. Use m.Inverse().Keys() to
+// iterate over the values.
+//
+// The keys are collected when Keys is called, so the iterator is not affected by later changes to
+// m.
+func (m *BiMap[K, V]) Keys() iterator.Iterator[K] {
+	m.m.RLock()
+	defer m.m.RUnlock()
+	keys := make([]K, 0, len(m.forward))
+	for k := range m.forward {
+		keys = append(keys, k)
+	}
+	return iterator.Slice(keys)
+}
@@ -0,0 +1,166 @@
+package xmaps
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/bradenaw/juniper/internal/fuzz"
+	"github.com/bradenaw/juniper/internal/require2"
+	"github.com/bradenaw/juniper/iterator"
+)
+
+func FuzzBiMap(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte) {
+		m := NewBiMap[byte, int]()
+		inverse := m.Inverse()
+		oracle := make(map[byte]int)
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				require2.Equal(t, len(oracle), m.Len())
+				require2.Equal(t, len(oracle), inverse.Len())
+				for k, v := range oracle {
+					gotV, ok := m.Get(k)
+					require2.True(t, ok)
+					require2.Equal(t, v, gotV)
+					gotK, ok := m.GetKey(v)
+					require2.True(t, ok)
+					require2.Equal(t, k, gotK)
+				}
+				require2.ElementsMatch(t, keys(oracle), iterator.Collect(m.Keys()))
+			},
+			func(k byte, v int) {
+				t.Logf("Put(%d, %d)", k, v)
+				m.Put(k, v)
+				for k2, v2 := range oracle {
+					if v2 == v {
+						delete(oracle, k2)
+					}
+				}
+				oracle[k] = v
+			},
+			func(k byte, v int) {
+				ok := m.TryPut(k, v)
+				t.Logf("TryPut(%d, %d) -> %t", k, v, ok)
+				existing, keyExists := oracle[k]
+				valueExists := false
+				for _, v2 := range oracle {
+					if v2 == v {
+						valueExists = true
+					}
+				}
+				expected := (keyExists && existing == v) || (!keyExists && !valueExists)
+				require2.Equal(t, expected, ok)
+				if ok {
+					oracle[k] = v
+				}
+			},
+			func(v int, k byte) {
+				t.Logf("Inverse().Put(%d, %d)", v, k)
+				inverse.Put(v, k)
+				for k2, v2 := range oracle {
+					if v2 == v {
+						delete(oracle, k2)
+					}
+				}
+				oracle[k] = v
+			},
+			func(k byte) {
+				t.Logf("DeleteKey(%d)", k)
+				m.DeleteKey(k)
+				delete(oracle, k)
+			},
+			func(v int) {
+				t.Logf("DeleteValue(%d)", v)
+				m.DeleteValue(v)
+				for k2, v2 := range oracle {
+					if v2 == v {
+						delete(oracle, k2)
+					}
+				}
+			},
+			func(k byte) {
+				_, expected := oracle[k]
+				require2.Equal(t, expected, m.ContainsKey(k))
+			},
+			func(v int) {
+				expected := false
+				for _, v2 := range oracle {
+					if v2 == v {
+						expected = true
+					}
+				}
+				require2.Equal(t, expected, m.ContainsValue(v))
+				require2.Equal(t, expected, inverse.ContainsKey(v))
+			},
+		)
+	})
+}
+
+func TestBiMapConcurrent(t *testing.T) {
+	m := NewBiMap[int, int]()
+	inverse := m.Inverse()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		i := i
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				m.Put(j%10, (i*1000+j)%10)
+				inverse.DeleteKey(j % 7)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				m.Get(j % 10)
+				inverse.ContainsKey(j % 10)
+				iterator.Collect(m.Keys())
+			}
+		}()
+	}
+	wg.Wait()
+
+	require2.Equal(t, m.Len(), inverse.Len())
+	for _, k := range iterator.Collect(m.Keys()) {
+		v, ok := m.Get(k)
+		require2.True(t, ok)
+		gotK, ok := m.GetKey(v)
+		require2.True(t, ok)
+		require2.Equal(t, k, gotK)
+	}
+}
+
+func keys[K comparable, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func ExampleBiMap() {
+	m := NewBiMap[int, string]()
+	m.Put(1, "one")
+	m.Put(2, "two")
+
+	fmt.Println(m.Get(1))
+	fmt.Println(m.GetKey("two"))
+
+	// Conflicting pairs are rejected by TryPut, and replaced by Put.
+	fmt.Println(m.TryPut(3, "two"))
+	m.Put(3, "two")
+	fmt.Println(m.Get(2))
+	fmt.Println(m.Inverse().Get("two"))
+
+	// Output:
+	// one true
+	// 2 true
+	// false
+	//  false
+	// 3 true
+}
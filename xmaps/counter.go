@@ -0,0 +1,118 @@
+package xmaps
+
+import (
+	"github.com/bradenaw/juniper/iterator"
+	"github.com/bradenaw/juniper/xsort"
+)
+
+// Counter[T] counts occurrences of items, also known as a multiset or bag. It is shorthand for
+// map[T]int with convenience methods.
+//
+// Items with a count of zero or less are never stored, so len(c) is the number of distinct items
+// with a positive count.
+type Counter[T comparable] map[T]int
+
+// ItemCount is an item and the number of times it appears in a Counter.
+type ItemCount[T any] struct {
+	Item  T
+	Count int
+}
+
+// CounterFromSlice returns a Counter with the number of times each item appears in items.
+func CounterFromSlice[T comparable](items []T) Counter[T] {
+	c := make(Counter[T])
+	for _, item := range items {
+		c[item]++
+	}
+	return c
+}
+
+// CounterFromIterator returns a Counter with the number of times each item appears in iter.
+func CounterFromIterator[T comparable](iter iterator.Iterator[T]) Counter[T] {
+	c := make(Counter[T])
+	for {
+		item, ok := iter.Next()
+		if !ok {
+			break
+		}
+		c[item]++
+	}
+	return c
+}
+
+// Add adds n to the count of item. n may be negative, in which case item is removed if its count
+// drops to zero or below.
+func (c Counter[T]) Add(item T, n int) {
+	count := c[item] + n
+	if count <= 0 {
+		delete(c, item)
+	} else {
+		c[item] = count
+	}
+}
+
+// Count returns the number of times item has been counted.
+func (c Counter[T]) Count(item T) int { return c[item] }
+
+// Total returns the sum of the counts of all items.
+func (c Counter[T]) Total() int {
+	total := 0
+	for _, count := range c {
+		total += count
+	}
+	return total
+}
+
+// MostCommon returns the k items with the highest counts and their counts, in descending order of
+// count. Items with the same count are in arbitrary order. If c has fewer than k items, MostCommon
+// returns all of them.
+func (c Counter[T]) MostCommon(k int) []ItemCount[T] {
+	items := make([]ItemCount[T], 0, len(c))
+	for item, count := range c {
+		items = append(items, ItemCount[T]{Item: item, Count: count})
+	}
+	return xsort.MinK(
+		func(a, b ItemCount[T]) bool { return a.Count > b.Count },
+		iterator.Slice(items),
+		k,
+	)
+}
+
+// Subtract subtracts the counts in other from the counts in c. Items whose count drops to zero or
+// below are removed.
+func (c Counter[T]) Subtract(other Counter[T]) {
+	for item, count := range other {
+		c.Add(item, -count)
+	}
+}
+
+// Union returns a Counter containing every item in c or other, with the larger of the two counts.
+func (c Counter[T]) Union(other Counter[T]) Counter[T] {
+	out := make(Counter[T], len(c))
+	for item, count := range c {
+		out[item] = count
+	}
+	for item, count := range other {
+		if count > out[item] {
+			out[item] = count
+		}
+	}
+	return out
+}
+
+// Intersection returns a Counter containing the items in both c and other, with the smaller of the
+// two counts.
+func (c Counter[T]) Intersection(other Counter[T]) Counter[T] {
+	out := make(Counter[T])
+	for item, count := range c {
+		otherCount, ok := other[item]
+		if !ok {
+			continue
+		}
+		if otherCount < count {
+			count = otherCount
+		}
+		out[item] = count
+	}
+	return out
+}
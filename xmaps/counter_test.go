@@ -0,0 +1,64 @@
+package xmaps
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/bradenaw/juniper/internal/require2"
+	"github.com/bradenaw/juniper/iterator"
+)
+
+func TestCounter(t *testing.T) {
+	c := CounterFromSlice([]string{"a", "b", "a", "c", "a", "b"})
+	require2.Equal(t, 3, c.Count("a"))
+	require2.Equal(t, 2, c.Count("b"))
+	require2.Equal(t, 0, c.Count("d"))
+	require2.Equal(t, 6, c.Total())
+
+	c.Add("d", 4)
+	c.Add("c", -1)
+	require2.Equal(t, 4, c.Count("d"))
+	require2.Equal(t, 0, c.Count("c"))
+	require2.Equal(t, 3, len(c))
+
+	require2.SlicesEqual(
+		t,
+		[]ItemCount[string]{{"d", 4}, {"a", 3}},
+		c.MostCommon(2),
+	)
+	require2.SlicesEqual(
+		t,
+		[]ItemCount[string]{{"d", 4}, {"a", 3}, {"b", 2}},
+		c.MostCommon(10),
+	)
+
+	c.Subtract(Counter[string]{"a": 1, "b": 5, "e": 1})
+	require2.DeepEqual(t, Counter[string]{"a": 2, "d": 4}, c)
+
+	c2 := CounterFromIterator(iterator.Slice([]string{"a", "a", "a", "b"}))
+	require2.DeepEqual(t, Counter[string]{"a": 3, "b": 1, "d": 4}, c.Union(c2))
+	require2.DeepEqual(t, Counter[string]{"a": 2}, c.Intersection(c2))
+}
+
+func ExampleCounter() {
+	statusCodes := []int{200, 200, 404, 500, 200, 404, 200, 503}
+	c := CounterFromSlice(statusCodes)
+
+	fmt.Println(c.Count(200))
+	fmt.Println(c.MostCommon(2))
+
+	// Items are removed when their counts reach zero.
+	c.Subtract(CounterFromSlice([]int{500, 503}))
+	codes := make([]int, 0, len(c))
+	for code := range c {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	fmt.Println(codes)
+
+	// Output:
+	// 4
+	// [{200 4} {404 2}]
+	// [200 404]
+}
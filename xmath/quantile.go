@@ -0,0 +1,374 @@
+package xmath
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// QuantileSketch estimates quantiles of a stream of numbers using bounded memory, using the
+// DDSketch algorithm (https://arxiv.org/abs/1908.10693).
+//
+// Values are counted in buckets whose bounds grow exponentially, so that every quantile estimate is
+// within a fixed relative error of a value that was actually added. Sketches with the same relative
+// accuracy can be merged exactly, giving the same result as if all of the values had been added to
+// one sketch, so sketches can be computed separately for each shard of a dataset and combined.
+//
+// QuantileSketch is not safe for concurrent use.
+type QuantileSketch struct {
+	relativeAccuracy float64
+	maxBuckets       int
+	gamma            float64
+	logGamma         float64
+
+	// Counts of positive values and of the negations of negative values.
+	positive quantileStore
+	negative quantileStore
+	zeros    uint64
+	count    uint64
+	min      float64
+	max      float64
+}
+
+// NewQuantileSketch returns an empty QuantileSketch. Quantile estimates are within relativeAccuracy
+// of the true value, for example relativeAccuracy 0.01 means the estimate of the 99th percentile is
+// within 1% of the true 99th percentile.
+//
+// At most maxBuckets buckets are kept each for positive and negative values, each taking 8 bytes.
+// With relativeAccuracy 0.01, 2048 buckets covers values from 1 microsecond to over a day without
+// losing accuracy. If more buckets are needed, the buckets for the values closest to zero are
+// combined, so the accuracy of low quantiles suffers first. maxBuckets <= 0 means there is no
+// limit.
+func NewQuantileSketch(relativeAccuracy float64, maxBuckets int) *QuantileSketch {
+	if relativeAccuracy <= 0 || relativeAccuracy >= 1 {
+		panic("QuantileSketch relative accuracy must be in (0, 1)")
+	}
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &QuantileSketch{
+		relativeAccuracy: relativeAccuracy,
+		maxBuckets:       maxBuckets,
+		gamma:            gamma,
+		logGamma:         math.Log(gamma),
+		min:              math.Inf(1),
+		max:              math.Inf(-1),
+	}
+}
+
+// key returns the index of the bucket for the positive value x.
+func (s *QuantileSketch) key(x float64) int {
+	return int(math.Ceil(math.Log(x) / s.logGamma))
+}
+
+// value returns the estimate for the values in the bucket for key, which is within
+// relativeAccuracy of every value in the bucket.
+func (s *QuantileSketch) value(key int) float64 {
+	return math.Exp(float64(key)*s.logGamma) * 2 / (s.gamma + 1)
+}
+
+// Add adds x to the sketch. NaN and infinite values are ignored.
+func (s *QuantileSketch) Add(x float64) {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return
+	}
+	switch {
+	case x > 0:
+		s.positive.add(s.key(x), 1, s.maxBuckets)
+	case x < 0:
+		s.negative.add(s.key(-x), 1, s.maxBuckets)
+	default:
+		s.zeros++
+	}
+	s.count++
+	if x < s.min {
+		s.min = x
+	}
+	if x > s.max {
+		s.max = x
+	}
+}
+
+// Count returns the number of values that have been added.
+func (s *QuantileSketch) Count() uint64 {
+	return s.count
+}
+
+// Min returns the smallest value that has been added, or NaN if none have.
+func (s *QuantileSketch) Min() float64 {
+	if s.count == 0 {
+		return math.NaN()
+	}
+	return s.min
+}
+
+// Max returns the largest value that has been added, or NaN if none have.
+func (s *QuantileSketch) Max() float64 {
+	if s.count == 0 {
+		return math.NaN()
+	}
+	return s.max
+}
+
+// Quantile returns an estimate of the q-th quantile of the values that have been added, for q
+// between 0 and 1. For example, Quantile(0.99) estimates the 99th percentile. Returns NaN if no
+// values have been added.
+func (s *QuantileSketch) Quantile(q float64) float64 {
+	if s.count == 0 {
+		return math.NaN()
+	}
+	if q <= 0 {
+		return s.min
+	} else if q >= 1 {
+		return s.max
+	}
+	rank := q * float64(s.count-1)
+
+	var result float64
+	seen := uint64(0)
+	found := false
+	// Negative values from most to least negative, then zeros, then positive values.
+	for i := len(s.negative.bins) - 1; i >= 0 && !found; i-- {
+		seen += s.negative.bins[i]
+		if float64(seen) > rank {
+			result = -s.value(s.negative.offset + i)
+			found = true
+		}
+	}
+	if !found {
+		seen += s.zeros
+		if float64(seen) > rank {
+			result = 0
+			found = true
+		}
+	}
+	for i := 0; i < len(s.positive.bins) && !found; i++ {
+		seen += s.positive.bins[i]
+		if float64(seen) > rank {
+			result = s.value(s.positive.offset + i)
+			found = true
+		}
+	}
+	if !found {
+		return s.max
+	}
+	// The bucket estimate may be slightly outside of the range of values actually seen.
+	if result < s.min {
+		return s.min
+	} else if result > s.max {
+		return s.max
+	}
+	return result
+}
+
+// CDF returns an estimate of the fraction of values that have been added that are <= x. Returns
+// NaN if no values have been added.
+func (s *QuantileSketch) CDF(x float64) float64 {
+	if s.count == 0 {
+		return math.NaN()
+	}
+	if x < s.min {
+		return 0
+	} else if x >= s.max {
+		return 1
+	}
+	n := uint64(0)
+	switch {
+	case x < 0:
+		// -y <= x for all y >= -x.
+		k := s.key(-x)
+		for i, c := range s.negative.bins {
+			if s.negative.offset+i >= k {
+				n += c
+			}
+		}
+	case x == 0:
+		n = s.negative.total() + s.zeros
+	default:
+		n = s.negative.total() + s.zeros
+		k := s.key(x)
+		for i, c := range s.positive.bins {
+			if s.positive.offset+i > k {
+				break
+			}
+			n += c
+		}
+	}
+	return float64(n) / float64(s.count)
+}
+
+// Merge adds all of the values that have been added to other to s.
+//
+// s and other must have the same relative accuracy, otherwise Merge returns an error.
+func (s *QuantileSketch) Merge(other *QuantileSketch) error {
+	if s.relativeAccuracy != other.relativeAccuracy {
+		return errors.New("QuantileSketches have different relative accuracies")
+	}
+	// Copy first in case other == s.
+	positive := append([]uint64(nil), other.positive.bins...)
+	negative := append([]uint64(nil), other.negative.bins...)
+	for i, c := range positive {
+		if c > 0 {
+			s.positive.add(other.positive.offset+i, c, s.maxBuckets)
+		}
+	}
+	for i, c := range negative {
+		if c > 0 {
+			s.negative.add(other.negative.offset+i, c, s.maxBuckets)
+		}
+	}
+	s.zeros += other.zeros
+	s.count += other.count
+	if other.min < s.min {
+		s.min = other.min
+	}
+	if other.max > s.max {
+		s.max = other.max
+	}
+	return nil
+}
+
+const quantileSketchVersion = 1
+
+var errInvalidQuantileSketch = errors.New("invalid QuantileSketch data")
+
+// MarshalBinary encodes the sketch so that it can be decoded by UnmarshalBinary.
+func (s *QuantileSketch) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	var scratch [binary.MaxVarintLen64]byte
+	writeUvarint := func(x uint64) {
+		buf.Write(scratch[:binary.PutUvarint(scratch[:], x)])
+	}
+	writeVarint := func(x int64) {
+		buf.Write(scratch[:binary.PutVarint(scratch[:], x)])
+	}
+
+	buf.WriteByte(quantileSketchVersion)
+	writeUvarint(math.Float64bits(s.relativeAccuracy))
+	writeVarint(int64(s.maxBuckets))
+	writeUvarint(s.zeros)
+	writeUvarint(math.Float64bits(s.min))
+	writeUvarint(math.Float64bits(s.max))
+	for _, store := range []*quantileStore{&s.positive, &s.negative} {
+		writeVarint(int64(store.offset))
+		writeUvarint(uint64(len(store.bins)))
+		for _, c := range store.bins {
+			writeUvarint(c)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the contents of s with the sketch encoded in data by MarshalBinary.
+func (s *QuantileSketch) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	version, err := r.ReadByte()
+	if err != nil {
+		return errInvalidQuantileSketch
+	}
+	if version != quantileSketchVersion {
+		return errors.New("unknown QuantileSketch data version")
+	}
+
+	var fields [5]uint64
+	for i := range fields {
+		if i == 1 {
+			var x int64
+			x, err = binary.ReadVarint(r)
+			fields[i] = uint64(x)
+		} else {
+			fields[i], err = binary.ReadUvarint(r)
+		}
+		if err != nil {
+			return errInvalidQuantileSketch
+		}
+	}
+	relativeAccuracy := math.Float64frombits(fields[0])
+	if !(relativeAccuracy > 0 && relativeAccuracy < 1) {
+		return errInvalidQuantileSketch
+	}
+	out := NewQuantileSketch(relativeAccuracy, int(int64(fields[1])))
+	out.zeros = fields[2]
+	out.min = math.Float64frombits(fields[3])
+	out.max = math.Float64frombits(fields[4])
+	out.count = out.zeros
+
+	for _, store := range []*quantileStore{&out.positive, &out.negative} {
+		offset, err := binary.ReadVarint(r)
+		if err != nil {
+			return errInvalidQuantileSketch
+		}
+		n, err := binary.ReadUvarint(r)
+		// Every bin takes at least one byte.
+		if err != nil || n > uint64(r.Len()) {
+			return errInvalidQuantileSketch
+		}
+		store.offset = int(offset)
+		if n > 0 {
+			store.bins = make([]uint64, n)
+		}
+		for i := range store.bins {
+			store.bins[i], err = binary.ReadUvarint(r)
+			if err != nil {
+				return errInvalidQuantileSketch
+			}
+			out.count += store.bins[i]
+		}
+	}
+	if r.Len() != 0 {
+		return errInvalidQuantileSketch
+	}
+	*s = *out
+	return nil
+}
+
+// quantileStore holds the counts for a contiguous range of bucket keys.
+type quantileStore struct {
+	// The key of bins[0].
+	offset int
+	bins   []uint64
+}
+
+// add adds n to the bucket for key, then combines the lowest buckets if there are more than
+// maxBuckets.
+func (s *quantileStore) add(key int, n uint64, maxBuckets int) {
+	if len(s.bins) == 0 {
+		s.offset = key
+		s.bins = append(s.bins, n)
+		return
+	}
+	if maxBuckets > 0 {
+		// Keys that would be combined into the lowest bucket anyway go straight there.
+		lowest := s.offset + len(s.bins) - maxBuckets
+		if key < lowest {
+			key = lowest
+		}
+	}
+	if key < s.offset {
+		grow := s.offset - key
+		bins := make([]uint64, len(s.bins)+grow)
+		copy(bins[grow:], s.bins)
+		s.bins = bins
+		s.offset = key
+	} else if key >= s.offset+len(s.bins) {
+		grow := key - (s.offset + len(s.bins)) + 1
+		s.bins = append(s.bins, make([]uint64, grow)...)
+	}
+	s.bins[key-s.offset] += n
+
+	if maxBuckets > 0 && len(s.bins) > maxBuckets {
+		excess := len(s.bins) - maxBuckets
+		for _, c := range s.bins[:excess] {
+			s.bins[excess] += c
+		}
+		s.bins = append(s.bins[:0], s.bins[excess:]...)
+		s.offset += excess
+	}
+}
+
+func (s *quantileStore) total() uint64 {
+	n := uint64(0)
+	for _, c := range s.bins {
+		n += c
+	}
+	return n
+}
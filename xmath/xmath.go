@@ -1,6 +1,11 @@
 // Package xmath contains extensions to the standard library package math.
 package xmath
 
+import (
+	"math"
+	"sort"
+)
+
 // Abs returns the absolute value of x. It panics if this value is not representable, for example
 // because -math.MinInt32 requires more than 32 bits to represent and so does not fit in an int32.
 func Abs[T ~int | ~int8 | ~int16 | ~int32 | ~int64](x T) T {
@@ -12,3 +17,46 @@ func Abs[T ~int | ~int8 | ~int16 | ~int32 | ~int64](x T) T {
 	}
 	return x
 }
+
+// number is any integer or floating-point type.
+type number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Median returns the median of xs, or NaN if xs is empty. It is the same as Percentile(xs, 50).
+func Median[T number](xs []T) float64 {
+	return Percentile(xs, 50)
+}
+
+// Percentile returns the p-th percentile of xs, for p between 0 and 100, or NaN if xs is empty.
+//
+// If the percentile falls between two elements of xs, it is linearly interpolated between them. For
+// example, the 50th percentile of [1, 2, 3, 4] is 2.5.
+//
+// Percentile does not modify xs, and takes O(n * log(n)) time to sort a copy of it. To compute
+// percentiles of data that is too large to hold in memory, or that arrives in separate shards, see
+// QuantileSketch.
+func Percentile[T number](xs []T, p float64) float64 {
+	if len(xs) == 0 {
+		return math.NaN()
+	}
+	sorted := make([]float64, len(xs))
+	for i, x := range xs {
+		sorted[i] = float64(x)
+	}
+	sort.Float64s(sorted)
+
+	if p <= 0 {
+		return sorted[0]
+	} else if p >= 100 {
+		return sorted[len(sorted)-1]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	i := int(rank)
+	frac := rank - float64(i)
+	if frac == 0 {
+		return sorted[i]
+	}
+	return sorted[i] + frac*(sorted[i+1]-sorted[i])
+}
@@ -0,0 +1,120 @@
+package xmath
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/bradenaw/juniper/internal/require2"
+)
+
+func TestPercentile(t *testing.T) {
+	require2.True(t, math.IsNaN(Percentile([]int{}, 50)))
+	require2.Equal(t, 2.5, Median([]int{4, 1, 3, 2}))
+	require2.Equal(t, 3.0, Median([]int{5, 1, 3}))
+	require2.Equal(t, 1.0, Percentile([]float64{3, 1, 2}, 0))
+	require2.Equal(t, 3.0, Percentile([]float64{3, 1, 2}, 100))
+	require2.Equal(t, 1.5, Percentile([]float64{3, 1, 2}, 25))
+	require2.Equal(t, 7.0, Percentile([]uint8{7}, 90))
+
+	// Doesn't modify the input.
+	xs := []int{3, 1, 2}
+	Median(xs)
+	require2.SlicesEqual(t, []int{3, 1, 2}, xs)
+}
+
+func TestQuantileSketch(t *testing.T) {
+	const relativeAccuracy = 0.01
+	r := rand.New(rand.NewSource(0))
+
+	check := func(t *testing.T, s *QuantileSketch, sorted []float64) {
+		require2.Equal(t, uint64(len(sorted)), s.Count())
+		require2.Equal(t, sorted[0], s.Min())
+		require2.Equal(t, sorted[len(sorted)-1], s.Max())
+		for _, q := range []float64{0, 0.01, 0.1, 0.25, 0.5, 0.75, 0.9, 0.99, 0.999, 1} {
+			expected := sorted[int(q*float64(len(sorted)-1))]
+			actual := s.Quantile(q)
+			require2.InDelta(t, actual, expected, math.Abs(expected)*relativeAccuracy+1e-9)
+
+			// The fraction of values <= the q-th quantile should be about q.
+			cdf := s.CDF(expected)
+			exact := float64(sort.SearchFloat64s(sorted, math.Nextafter(expected, math.Inf(1)))) /
+				float64(len(sorted))
+			require2.InDelta(t, cdf, exact, 0.02)
+		}
+	}
+
+	distributions := []struct {
+		name string
+		f    func() float64
+	}{
+		{"Exponential", r.ExpFloat64},
+		{"Normal", func() float64 { return r.NormFloat64() * 100 }},
+		{"LogNormal", func() float64 { return math.Exp(r.NormFloat64() * 3) }},
+		{"WithZeros", func() float64 { return float64(r.Intn(5)) }},
+	}
+	for _, dist := range distributions {
+		t.Run(dist.name, func(t *testing.T) {
+			// Split across shards and merged, as if computed in parallel.
+			shards := []*QuantileSketch{
+				NewQuantileSketch(relativeAccuracy, 2048),
+				NewQuantileSketch(relativeAccuracy, 2048),
+				NewQuantileSketch(relativeAccuracy, 2048),
+			}
+			all := NewQuantileSketch(relativeAccuracy, 2048)
+			values := make([]float64, 100000)
+			for i := range values {
+				values[i] = dist.f()
+				shards[i%len(shards)].Add(values[i])
+				all.Add(values[i])
+			}
+			sort.Float64s(values)
+			check(t, all, values)
+
+			merged := NewQuantileSketch(relativeAccuracy, 2048)
+			for _, shard := range shards {
+				require2.NoError(t, merged.Merge(shard))
+			}
+			check(t, merged, values)
+
+			data, err := merged.MarshalBinary()
+			require2.NoError(t, err)
+			t.Logf("%d bytes encoded", len(data))
+			var decoded QuantileSketch
+			require2.NoError(t, decoded.UnmarshalBinary(data))
+			check(t, &decoded, values)
+			for i := 0; i <= 100; i++ {
+				q := float64(i) / 100
+				require2.Equal(t, merged.Quantile(q), decoded.Quantile(q))
+			}
+		})
+	}
+}
+
+func TestQuantileSketchMaxBuckets(t *testing.T) {
+	s := NewQuantileSketch(0.01, 100)
+	for i := 0; i < 1000; i++ {
+		s.Add(math.Pow(1.1, float64(i%200)))
+	}
+	require2.LessOrEqual(t, len(s.positive.bins), 100)
+	// High quantiles are still accurate.
+	require2.InDelta(t, s.Quantile(1), math.Pow(1.1, 199), math.Pow(1.1, 199)*0.01)
+	require2.InDelta(t, s.Quantile(0.99), math.Pow(1.1, 197), math.Pow(1.1, 197)*0.01)
+
+	require2.Error(t, s.Merge(NewQuantileSketch(0.02, 100)))
+	require2.True(t, math.IsNaN(NewQuantileSketch(0.01, 0).Quantile(0.5)))
+}
+
+func TestQuantileSketchUnmarshalInvalid(t *testing.T) {
+	s := NewQuantileSketch(0.01, 0)
+	s.Add(1)
+	s.Add(-5)
+	s.Add(0)
+	data, err := s.MarshalBinary()
+	require2.NoError(t, err)
+	for i := 0; i < len(data); i++ {
+		var decoded QuantileSketch
+		require2.Error(t, decoded.UnmarshalBinary(data[:i]))
+	}
+}
@@ -0,0 +1,135 @@
+package xsort
+
+// SortedSlice is a set of items kept in a slice in sorted order according to less. Items a and b
+// are considered the same if !less(a, b) && !less(b, a).
+//
+// Lookups use binary search. Inserting and removing take O(n) time since they shift the rest of
+// the slice, but for sets of up to a few hundred items a SortedSlice is usually faster and smaller
+// than a tree.Set. Unlike a tree.Set, the items and any range of them can be used directly as a
+// slice.
+type SortedSlice[T any] struct {
+	less  Less[T]
+	items []T
+}
+
+// NewSortedSlice returns a SortedSlice that uses less to order items, containing items. items is
+// not modified.
+func NewSortedSlice[T any](less Less[T], items ...T) *SortedSlice[T] {
+	return &SortedSlice[T]{
+		less:  less,
+		items: sortedUnique(less, items),
+	}
+}
+
+// sortedUnique returns a sorted copy of items with duplicates removed, keeping the first of any
+// duplicates.
+func sortedUnique[T any](less Less[T], items []T) []T {
+	out := append([]T(nil), items...)
+	SliceStable(out, less)
+	j := 0
+	for i := range out {
+		if i == 0 || less(out[j-1], out[i]) {
+			out[j] = out[i]
+			j++
+		}
+	}
+	var zero T
+	for i := j; i < len(out); i++ {
+		out[i] = zero
+	}
+	return out[:j]
+}
+
+// Len returns the number of items in s.
+func (s *SortedSlice[T]) Len() int {
+	return len(s.items)
+}
+
+// Items returns all of the items in s in sorted order. The returned slice is only valid until s is
+// next modified, and must not be modified itself.
+func (s *SortedSlice[T]) Items() []T {
+	return s.items
+}
+
+// At returns the i-th smallest item in s.
+func (s *SortedSlice[T]) At(i int) T {
+	return s.items[i]
+}
+
+// Index returns the position of item in s and true if it is present. Otherwise, it returns the
+// position item would be inserted at, which may be s.Len(), and false.
+func (s *SortedSlice[T]) Index(item T) (int, bool) {
+	i := s.search(item)
+	return i, i < len(s.items) && !s.less(item, s.items[i])
+}
+
+// search returns the index of the first item in s that is not less than item.
+func (s *SortedSlice[T]) search(item T) int {
+	return Search(s.items, s.less, item)
+}
+
+// Contains returns true if item is present in s.
+func (s *SortedSlice[T]) Contains(item T) bool {
+	_, ok := s.Index(item)
+	return ok
+}
+
+// Insert adds item to s and returns true if it is not already present. Otherwise, s is unchanged
+// and Insert returns false.
+func (s *SortedSlice[T]) Insert(item T) bool {
+	i, ok := s.Index(item)
+	if ok {
+		return false
+	}
+	var zero T
+	s.items = append(s.items, zero)
+	copy(s.items[i+1:], s.items[i:])
+	s.items[i] = item
+	return true
+}
+
+// Remove removes item from s and returns true if it is present. Otherwise, s is unchanged and
+// Remove returns false.
+func (s *SortedSlice[T]) Remove(item T) bool {
+	i, ok := s.Index(item)
+	if !ok {
+		return false
+	}
+	copy(s.items[i:], s.items[i+1:])
+	var zero T
+	s.items[len(s.items)-1] = zero
+	s.items = s.items[:len(s.items)-1]
+	return true
+}
+
+// Range returns the items in s that are greater than or equal to lower and less than upper, in
+// sorted order. The returned slice is only valid until s is next modified, and must not be
+// modified itself.
+func (s *SortedSlice[T]) Range(lower T, upper T) []T {
+	i := s.search(lower)
+	j := s.search(upper)
+	if j < i {
+		return s.items[i:i]
+	}
+	return s.items[i:j]
+}
+
+// Merge adds all of items to s. Items that are already present in s are ignored, as are all but
+// the first of any duplicates within items. items is not modified.
+//
+// Merge takes O(n + m * log(n + m)) time, where n is s.Len() and m is len(items), which is faster
+// than calling Insert for each item unless there are very few of them.
+func (s *SortedSlice[T]) Merge(items ...T) {
+	added := sortedUnique(s.less, items)
+	j := 0
+	for _, item := range added {
+		if !s.Contains(item) {
+			added[j] = item
+			j++
+		}
+	}
+	if j == 0 {
+		return
+	}
+	s.items = MergeSlices(s.less, nil, s.items, added[:j])
+}
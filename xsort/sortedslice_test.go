@@ -0,0 +1,110 @@
+package xsort_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bradenaw/juniper/internal/fuzz"
+	"github.com/bradenaw/juniper/internal/require2"
+	"github.com/bradenaw/juniper/xsort"
+)
+
+func FuzzSortedSlice(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte) {
+		s := xsort.NewSortedSlice(xsort.OrderedLess[byte])
+		oracle := make(map[byte]struct{})
+		expectedItems := func() []byte {
+			var out []byte
+			for i := 0; i < 256; i++ {
+				if _, ok := oracle[byte(i)]; ok {
+					out = append(out, byte(i))
+				}
+			}
+			return out
+		}
+
+		fuzz.Operations(
+			b,
+			func() { // check
+				require2.Equal(t, len(oracle), s.Len())
+				require2.SlicesEqual(t, expectedItems(), s.Items())
+			},
+			func(x byte) {
+				_, present := oracle[x]
+				t.Logf("Insert(%d)", x)
+				require2.Equal(t, !present, s.Insert(x))
+				oracle[x] = struct{}{}
+			},
+			func(x byte) {
+				_, present := oracle[x]
+				t.Logf("Remove(%d)", x)
+				require2.Equal(t, present, s.Remove(x))
+				delete(oracle, x)
+			},
+			func(x byte) {
+				_, present := oracle[x]
+				require2.Equal(t, present, s.Contains(x))
+				i, ok := s.Index(x)
+				require2.Equal(t, present, ok)
+				expectedIndex := 0
+				for _, item := range expectedItems() {
+					if item < x {
+						expectedIndex++
+					}
+				}
+				require2.Equal(t, expectedIndex, i)
+				if ok {
+					require2.Equal(t, x, s.At(i))
+				}
+			},
+			func(lower byte, upper byte) {
+				var expected []byte
+				for _, item := range expectedItems() {
+					if item >= lower && item < upper {
+						expected = append(expected, item)
+					}
+				}
+				require2.SlicesEqual(t, expected, s.Range(lower, upper))
+			},
+			func(x, y, z byte) {
+				t.Logf("Merge(%d, %d, %d)", x, y, z)
+				s.Merge(x, y, z)
+				oracle[x] = struct{}{}
+				oracle[y] = struct{}{}
+				oracle[z] = struct{}{}
+			},
+		)
+	})
+}
+
+func TestSortedSliceKeepsFirst(t *testing.T) {
+	type kv struct {
+		k string
+		v int
+	}
+	less := func(a, b kv) bool { return a.k < b.k }
+	s := xsort.NewSortedSlice(less, kv{"b", 1}, kv{"a", 1}, kv{"b", 2})
+	require2.SlicesEqual(t, []kv{{"a", 1}, {"b", 1}}, s.Items())
+
+	require2.True(t, !s.Insert(kv{"a", 2}))
+	s.Merge(kv{"c", 1}, kv{"a", 3}, kv{"c", 2})
+	require2.SlicesEqual(t, []kv{{"a", 1}, {"b", 1}, {"c", 1}}, s.Items())
+}
+
+func ExampleSortedSlice() {
+	s := xsort.NewSortedSlice(xsort.OrderedLess[int], 50, 10, 40, 10)
+	s.Insert(30)
+	s.Remove(40)
+	s.Merge(20, 60, 30)
+
+	fmt.Println(s.Items())
+	fmt.Println(s.Contains(40))
+	fmt.Println(s.Index(35))
+	fmt.Println(s.Range(15, 50))
+
+	// Output:
+	// [10 20 30 50 60]
+	// false
+	// 3 false
+	// [20 30]
+}